@@ -0,0 +1,242 @@
+// Package audit keeps a JSON lines log of the up and down runs of a project,
+// recording who ran what and how it went, for shared hosts.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dockermiUtils "github.com/mkhuda/dockermi/utils"
+)
+
+// Defaults of the log returned by Open.
+const (
+	// MaxSize is the size in bytes above which the log is rotated.
+	MaxSize = 1 << 20
+	// Backups is the number of rotated logs kept: audit.jsonl.1 (the newest)
+	// to audit.jsonl.<Backups>.
+	Backups = 5
+)
+
+// Outcomes of a run.
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+// Record is the audit record of one run.
+type Record struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	Host string    `json:"host"`
+	Cwd  string    `json:"cwd"`
+	// Command is the action run ("up" or "down") and Args the options passed
+	// on to compose.
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Group is the group key when a group script was run.
+	Group    string   `json:"group,omitempty"`
+	Script   string   `json:"script"`
+	Services []string `json:"services"`
+	Outcome  string   `json:"outcome"`
+	Error    string   `json:"error,omitempty"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+	// Version is the dockermi version that ran the script, and ScriptVersion
+	// the one that generated it.
+	Version       string `json:"version"`
+	ScriptVersion string `json:"script_version,omitempty"`
+}
+
+// NewRecord returns a record of command started now by the current user.
+func NewRecord(command string) Record {
+	host, _ := os.Hostname()
+	cwd, _ := os.Getwd()
+	return Record{Time: time.Now(), User: CurrentUser(), Host: host, Cwd: cwd, Command: command}
+}
+
+// Finish sets the outcome and duration of the run from err and its start time.
+func (r *Record) Finish(err error) {
+	r.Duration = time.Since(r.Time).Seconds()
+	r.Outcome = Success
+	if err != nil {
+		r.Outcome = Failure
+		r.Error = err.Error()
+	}
+}
+
+// CurrentUser returns the name of the user running dockermi. Under sudo, the
+// user who invoked sudo is returned.
+func CurrentUser() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+// Log is an audit log file, rotated when it grows above MaxSize.
+type Log struct {
+	Path    string
+	MaxSize int64
+	Backups int
+}
+
+// Open returns the audit log of the project at root, .dockermi/audit.jsonl.
+func Open(root string) Log {
+	return Log{Path: filepath.Join(root, ".dockermi", "audit.jsonl"), MaxSize: MaxSize, Backups: Backups}
+}
+
+// Append adds record to the log, rotating it first when it is full. The log
+// is shared by everyone running dockermi in the project, so it is created
+// writable by the group, in a directory writable by the group.
+func (l Log) Append(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := dockermiUtils.MkdirShared(filepath.Dir(l.Path)); err != nil {
+		return err
+	}
+	if info, err := os.Stat(l.Path); err == nil && info.Size()+int64(len(data)) >= l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("cannot rotate %s: %w", l.Path, err)
+		}
+	}
+
+	file, err := openLog(l.Path)
+	if err != nil {
+		return err
+	}
+	// A single write keeps records of concurrent runs on separate lines
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// openLog opens the log at path for appending. A log created here is made
+// writable by the group regardless of the umask; an existing log keeps the
+// mode it has.
+func openLog(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0664)
+	if os.IsExist(err) {
+		return os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(0664); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// rotate shifts the rotated logs by one, dropping the oldest, and moves the
+// log to audit.jsonl.1.
+func (l Log) rotate() error {
+	if l.Backups < 1 {
+		return os.Remove(l.Path)
+	}
+	for i := l.Backups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backup(i), l.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.Path, l.backup(1))
+}
+
+// backup returns the path of the i-th rotated log.
+func (l Log) backup(i int) string {
+	return fmt.Sprintf("%s.%d", l.Path, i)
+}
+
+// Read returns the records of the log and of the rotated logs matching
+// filter, oldest first. A missing log holds no records.
+func (l Log) Read(filter Filter) ([]Record, error) {
+	var records []Record
+	for i := l.Backups; i >= 0; i-- {
+		path := l.Path
+		if i > 0 {
+			path = l.backup(i)
+		}
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for line := 1; scanner.Scan(); line++ {
+			if len(strings.TrimSpace(scanner.Text())) == 0 {
+				continue
+			}
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("invalid record at %s:%d: %w", path, line, err)
+			}
+			if filter.Match(record) {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Filter selects records. Empty fields match every record.
+type Filter struct {
+	Service string
+	User    string
+	Group   string
+	// Since and Until bound the time of the records, Until excluded.
+	Since, Until time.Time
+}
+
+// Match reports whether record is selected by f.
+func (f Filter) Match(record Record) bool {
+	if f.User != "" && record.User != f.User {
+		return false
+	}
+	if f.Group != "" && record.Group != f.Group {
+		return false
+	}
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !record.Time.Before(f.Until) {
+		return false
+	}
+	if f.Service == "" {
+		return true
+	}
+	for _, service := range record.Services {
+		if service == f.Service {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package audit_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/audit"
+)
+
+func TestAuditLog(t *testing.T) {
+	log := audit.Open(t.TempDir())
+	log.MaxSize, log.Backups = 600, 2
+
+	started := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	users := []string{"ana", "ben"}
+	for i := 0; i < 12; i++ {
+		record := audit.Record{
+			Time:     started.Add(time.Duration(i) * time.Hour),
+			User:     users[i%2],
+			Command:  "up",
+			Services: []string{"db", "api"},
+			Outcome:  audit.Success,
+		}
+		if i%3 == 0 {
+			record.Services = []string{"worker"}
+		}
+		if err := log.Append(record); err != nil {
+			t.Fatalf("Error appending record: %v", err)
+		}
+	}
+	if _, err := os.Stat(log.Path + ".2"); err != nil {
+		t.Fatalf("Expected the log to be rotated: %v", err)
+	}
+	if _, err := os.Stat(log.Path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 2 rotated logs to be kept, got: %v", err)
+	}
+
+	all, err := log.Read(audit.Filter{})
+	if err != nil {
+		t.Fatalf("Error reading the log: %v", err)
+	}
+	if len(all) == 0 || len(all) >= 12 || !all[len(all)-1].Time.Equal(started.Add(11*time.Hour)) {
+		t.Fatalf("Expected the newest records oldest first, got %d: %+v", len(all), all)
+	}
+	for i := 1; i < len(all); i++ {
+		if !all[i-1].Time.Before(all[i].Time) {
+			t.Fatalf("Expected records oldest first, got: %v then %v", all[i-1].Time, all[i].Time)
+		}
+	}
+
+	filter := audit.Filter{Service: "worker", User: "ben", Since: started.Add(5 * time.Hour), Until: started.Add(10 * time.Hour)}
+	records, err := log.Read(filter)
+	if err != nil {
+		t.Fatalf("Error reading the log: %v", err)
+	}
+	// worker runs at 0h, 3h, 6h and 9h; ben at odd hours
+	if len(records) != 1 || !records[0].Time.Equal(started.Add(9*time.Hour)) {
+		t.Fatalf("Expected ben's 9h worker run, got: %+v", records)
+	}
+
+	record := audit.NewRecord("down")
+	record.Finish(fmt.Errorf("exit status 1"))
+	if record.User == "" || record.Outcome != audit.Failure || record.Error != "exit status 1" || record.Duration < 0 {
+		t.Fatalf("Unexpected finished record: %+v", record)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package audit_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/audit"
+)
+
+func TestAuditLogPermissions(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(022))
+
+	log := audit.Open(t.TempDir())
+	if err := log.Append(audit.Record{Command: "up", Outcome: audit.Success}); err != nil {
+		t.Fatalf("Error appending record: %v", err)
+	}
+
+	// Everyone in the group running dockermi must be able to add records
+	dir, err := os.Stat(filepath.Dir(log.Path))
+	if err != nil {
+		t.Fatalf("Error reading the log directory: %v", err)
+	}
+	if perm := dir.Mode().Perm(); perm != 0775 {
+		t.Fatalf("Expected the log directory to be created 0775, got %o", perm)
+	}
+	file, err := os.Stat(log.Path)
+	if err != nil {
+		t.Fatalf("Error reading the log: %v", err)
+	}
+	if perm := file.Mode().Perm(); perm != 0664 {
+		t.Fatalf("Expected the log to be created 0664, got %o", perm)
+	}
+
+	// An existing log keeps its mode
+	if err := os.Chmod(log.Path, 0640); err != nil {
+		t.Fatalf("Error changing the log mode: %v", err)
+	}
+	if err := log.Append(audit.Record{Command: "down", Outcome: audit.Success}); err != nil {
+		t.Fatalf("Error appending record: %v", err)
+	}
+	if file, err = os.Stat(log.Path); err != nil {
+		t.Fatalf("Error reading the log: %v", err)
+	}
+	if perm := file.Mode().Perm(); perm != 0640 {
+		t.Fatalf("Expected the existing log to keep its mode, got %o", perm)
+	}
+}
@@ -0,0 +1,156 @@
+// Package cache stores parsed compose files between dockermi runs.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkhuda/dockermi/internal/registry"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// formatVersion is bumped whenever the parsed representation changes, so that
+// entries written by older versions are parsed again.
+const formatVersion = 1
+
+// entry is the cached result of parsing one compose file.
+type entry struct {
+	Version  int                              `json:"version"`
+	Path     string                           `json:"path"`
+	Size     int64                            `json:"size"`
+	ModTime  int64                            `json:"mod_time"`
+	SHA256   string                           `json:"sha256"`
+	Services map[string]DockermiTypes.Service `json:"services"`
+}
+
+// Cache is a directory holding one entry per compose file. An entry is used
+// while the path, size and modification time of the file match, or when its
+// content hash still matches after they changed. Entries are written
+// atomically, so a Cache may be shared by concurrent parsers.
+type Cache struct {
+	dir string
+}
+
+// Open returns the cache in the dockermi home directory (~/.dockermi/cache).
+func Open() (*Cache, error) {
+	dockermiDir, err := registry.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(dockermiDir, "cache")), nil
+}
+
+// New returns a cache stored in dir, which is created on first write.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Load returns the services of the compose file at path, from the cache when
+// the file did not change, otherwise by calling parse and caching its result.
+// The returned bool reports a cache hit. The file is only read and hashed when
+// its size or modification time differ from the entry; when only those changed,
+// the entry is kept and updated. Failing to write the cache is not an error.
+func (c *Cache) Load(path string, parse func(content []byte) (map[string]DockermiTypes.Service, error)) (map[string]DockermiTypes.Service, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	current := entry{
+		Version: formatVersion,
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+	}
+
+	entryPath := c.entryPath(path)
+	var cached entry
+	found := false
+	if data, err := os.ReadFile(entryPath); err == nil {
+		// A corrupt entry is treated as a miss and replaced
+		found = json.Unmarshal(data, &cached) == nil && cached.Version == current.Version && cached.Path == current.Path
+	}
+	if found && cached.Size == current.Size && cached.ModTime == current.ModTime {
+		return cached.Services, true, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(content)
+	current.SHA256 = hex.EncodeToString(sum[:])
+
+	// The file was touched or copied without changing its content
+	if found && cached.SHA256 == current.SHA256 {
+		current.Services = cached.Services
+		c.write(entryPath, current)
+		return cached.Services, true, nil
+	}
+
+	services, err := parse(content)
+	if err != nil {
+		return nil, false, err
+	}
+	current.Services = services
+	c.write(entryPath, current)
+	return services, false, nil
+}
+
+// Clear removes every entry and returns how many were removed.
+func (c *Cache) Clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// entryPath returns the file holding the entry of the compose file at path.
+func (c *Cache) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// write stores e at entryPath through a temporary file, ignoring failures.
+func (c *Cache) write(entryPath string, e entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return
+	}
+	file, err := os.CreateTemp(c.dir, ".entry-*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name()) // no-op once the file has been renamed
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err != nil || closeErr != nil {
+		return
+	}
+	os.Rename(file.Name(), entryPath)
+}
@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/cache"
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestParseCache(t *testing.T) {
+	projectDir := t.TempDir()
+	composeFile := filepath.Join(projectDir, "docker-compose.yml")
+	compose := "services:\n  web:\n    image: nginx\n    labels:\n      dockermi.order: \"1\"\n      dockermi.active: \"true\"\n"
+	if err := os.WriteFile(composeFile, []byte(compose), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	parseCache := cache.New(filepath.Join(t.TempDir(), "cache"))
+	load := func() (map[string]types.Service, bool) {
+		t.Helper()
+		services, hit, err := parseCache.Load(composeFile, dockercompose.ParseCompose)
+		if err != nil {
+			t.Fatalf("Error loading compose file: %v", err)
+		}
+		return services, hit
+	}
+
+	if _, hit := load(); hit {
+		t.Fatalf("Expected a miss for an empty cache")
+	}
+	if services, hit := load(); !hit || services["web"].Image != "nginx" {
+		t.Fatalf("Expected a hit with the cached service, got %v: %+v", hit, services)
+	}
+
+	if err := os.WriteFile(composeFile, []byte(strings.Replace(compose, "nginx", "httpd", 1)), 0644); err != nil {
+		t.Fatalf("Error updating compose file: %v", err)
+	}
+	if services, hit := load(); hit || services["web"].Image != "httpd" {
+		t.Fatalf("Expected a changed file to be parsed again, got %v: %+v", hit, services)
+	}
+
+	// Touching the file without changing it keeps the entry
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(composeFile, later, later); err != nil {
+		t.Fatalf("Error touching compose file: %v", err)
+	}
+	if services, hit := load(); !hit || services["web"].Image != "httpd" {
+		t.Fatalf("Expected a touched file to be a hit, got %v: %+v", hit, services)
+	}
+
+	// An unchanged size and modification time is a hit without reading the file
+	if err := os.WriteFile(composeFile, []byte(strings.Replace(compose, "nginx", "caddy", 1)), 0644); err != nil {
+		t.Fatalf("Error updating compose file: %v", err)
+	}
+	if err := os.Chtimes(composeFile, later, later); err != nil {
+		t.Fatalf("Error touching compose file: %v", err)
+	}
+	if services, hit := load(); !hit || services["web"].Image != "httpd" {
+		t.Fatalf("Expected the entry to be used without hashing the file, got %v: %+v", hit, services)
+	}
+	if err := os.Chtimes(composeFile, time.Now(), time.Now()); err != nil {
+		t.Fatalf("Error touching compose file: %v", err)
+	}
+	if services, hit := load(); hit || services["web"].Image != "caddy" {
+		t.Fatalf("Expected a new modification time and content to be parsed again, got %v: %+v", hit, services)
+	}
+
+	// Discovery goes through the cache once it is set
+	dockercompose.Cache = parseCache
+	defer func() { dockercompose.Cache = nil }()
+	services, err := dockercompose.FindServices(projectDir, false)
+	if err != nil || len(services) != 1 || services[0].Definition.Image != "caddy" {
+		t.Fatalf("Expected the cached service, got: %+v (%v)", services, err)
+	}
+
+	entries, err := os.ReadDir(parseCache.Dir())
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one cache entry, got: %v (%v)", entries, err)
+	}
+	if err := os.WriteFile(filepath.Join(parseCache.Dir(), entries[0].Name()), []byte("{"), 0644); err != nil {
+		t.Fatalf("Error corrupting cache entry: %v", err)
+	}
+	if services, hit := load(); hit || services["web"].Image != "caddy" {
+		t.Fatalf("Expected a corrupt entry to be parsed again, got %v: %+v", hit, services)
+	}
+
+	if removed, err := parseCache.Clear(); err != nil || removed != 1 {
+		t.Fatalf("Expected one entry to be cleared, got %d (%v)", removed, err)
+	}
+	if _, hit := load(); hit {
+		t.Fatalf("Expected a miss after clearing the cache")
+	}
+}
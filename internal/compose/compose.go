@@ -0,0 +1,161 @@
+// Package compose knows the compose implementations dockermi can drive and
+// detects which one is installed.
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Default is the tool used when none is given or detected.
+const Default = "docker-compose"
+
+// detectTimeout bounds every command run while detecting the installed tool.
+const detectTimeout = 5 * time.Second
+
+// Tool is a compose implementation.
+type Tool struct {
+	// Name identifies the tool in --compose and in script headers.
+	Name string
+	// Command is the command line invoking compose, e.g. ["docker", "compose"].
+	Command []string
+	// Podman is true when containers are run by Podman. Podman tools do not
+	// all support 'ps --services --filter', so running containers are queried
+	// through 'podman ps' instead.
+	Podman bool
+}
+
+// String returns the command line of the tool.
+func (t Tool) String() string {
+	return strings.Join(t.Command, " ")
+}
+
+// Tools lists the supported compose implementations in detection order.
+var Tools = []Tool{
+	{Name: "docker-compose", Command: []string{"docker-compose"}},
+	{Name: "docker", Command: []string{"docker", "compose"}},
+	// podman-docker is the docker command of the podman-docker shim, which
+	// runs Podman; it is detected through the docker command.
+	{Name: "podman-docker", Command: []string{"docker", "compose"}, Podman: true},
+	{Name: "podman-compose", Command: []string{"podman-compose"}, Podman: true},
+	{Name: "podman", Command: []string{"podman", "compose"}, Podman: true},
+}
+
+// Names returns the names of the supported tools.
+func Names() []string {
+	names := make([]string, len(Tools))
+	for i, tool := range Tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// Lookup returns the tool with the given name, or the Default tool for an empty name.
+func Lookup(name string) (Tool, error) {
+	if name == "" {
+		name = Default
+	}
+	for _, tool := range Tools {
+		if tool.Name == name {
+			return tool, nil
+		}
+	}
+	return Tool{}, fmt.Errorf("unknown compose tool: %s (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Detect returns the first of Tools installed on PATH. Plugins ('docker
+// compose', 'podman compose') only count when their 'compose version' command
+// succeeds. A docker command that is the podman-docker shim is reported as the
+// podman-docker tool.
+func Detect() (Tool, error) {
+	for _, tool := range Tools {
+		if tool.Name == "podman-docker" {
+			continue
+		}
+		path, err := exec.LookPath(tool.Command[0])
+		if err != nil {
+			continue
+		}
+		if len(tool.Command) > 1 && run(path, append(tool.Command[1:], "version")...) != nil {
+			continue
+		}
+		if tool.Name == "docker" && isPodmanShim(path) {
+			return Lookup("podman-docker")
+		}
+		return tool, nil
+	}
+	return Tool{}, fmt.Errorf("no compose tool found on PATH (looked for: %s)", strings.Join(Names(), ", "))
+}
+
+// isPodmanShim reports whether the docker command at path is Podman in disguise.
+func isPodmanShim(path string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	return err == nil && strings.Contains(strings.ToLower(string(out)), "podman")
+}
+
+// run runs the command quietly and returns its error.
+func run(path string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, path, args...).Run()
+}
+
+// Target selects the engine compose talks to: a named context (docker
+// context, or podman system connection) or the address of an engine host.
+// The zero Target leaves the choice to the environment.
+type Target struct {
+	Context string
+	Host    string
+}
+
+// Empty reports whether the target selects no engine.
+func (t Target) Empty() bool {
+	return t.Context == "" && t.Host == ""
+}
+
+// Validate returns an error when both a context and a host are given.
+func (t Target) Validate() error {
+	if t.Context != "" && t.Host != "" {
+		return fmt.Errorf("--context and --host cannot be used together")
+	}
+	return nil
+}
+
+// String describes the target, e.g. "context remote" or "host ssh://build".
+func (t Target) String() string {
+	switch {
+	case t.Context != "":
+		return "context " + t.Context
+	case t.Host != "":
+		return "host " + t.Host
+	default:
+		return "default engine"
+	}
+}
+
+// Variables returns the names of the environment variables tool reads to
+// select a context and a host.
+func (t Tool) Variables() (context, host string) {
+	if t.Podman {
+		return "CONTAINER_CONNECTION", "CONTAINER_HOST"
+	}
+	return "DOCKER_CONTEXT", "DOCKER_HOST"
+}
+
+// Env returns the environment entries (NAME=value) making tool use target.
+func (t Target) Env(tool Tool) []string {
+	context, host := tool.Variables()
+	switch {
+	case t.Context != "":
+		return []string{context + "=" + t.Context}
+	case t.Host != "":
+		return []string{host + "=" + t.Host}
+	default:
+		return nil
+	}
+}
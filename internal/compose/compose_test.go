@@ -0,0 +1,60 @@
+package compose_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+)
+
+// writeFakeTool writes an executable shell script named name into dir.
+func writeFakeTool(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("Error writing fake %s: %v", name, err)
+	}
+}
+
+func TestComposeDetect(t *testing.T) {
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+
+	if _, err := compose.Detect(); err == nil {
+		t.Fatalf("Expected an error when no compose tool is installed")
+	}
+
+	// podman only counts when its compose plugin answers
+	writeFakeTool(t, binDir, "podman", "[ \"$1 $2\" = \"compose version\" ] || exit 1\n")
+	tool, err := compose.Detect()
+	if err != nil || tool.Name != "podman" || !tool.Podman || tool.String() != "podman compose" {
+		t.Fatalf("Expected podman compose, got: %+v (%v)", tool, err)
+	}
+
+	// The podman-docker shim is the docker command, but runs Podman
+	writeFakeTool(t, binDir, "docker", "[ \"$1\" = \"--version\" ] && echo \"podman version 4.9.3\"\nexit 0\n")
+	tool, err = compose.Detect()
+	if err != nil || tool.Name != "podman-docker" || !tool.Podman || tool.String() != "docker compose" {
+		t.Fatalf("Expected the podman-docker shim, got: %+v (%v)", tool, err)
+	}
+	if shim, err := compose.Lookup(tool.Name); err != nil || !shim.Podman {
+		t.Fatalf("Expected the podman-docker shim to be found by name, got: %+v (%v)", shim, err)
+	}
+
+	writeFakeTool(t, binDir, "docker-compose", "exit 0\n")
+	tool, err = compose.Detect()
+	if err != nil || tool.Name != "docker-compose" || tool.Podman {
+		t.Fatalf("Expected docker-compose, got: %+v (%v)", tool, err)
+	}
+
+	// DOCKERMI_COMPOSE is resolved by the caller, so it is not read here
+	t.Setenv("DOCKERMI_COMPOSE", "auto")
+	tool, err = compose.Detect()
+	if err != nil || tool.Name != "docker-compose" {
+		t.Fatalf("Expected docker-compose, got: %+v (%v)", tool, err)
+	}
+
+	if _, err := compose.Lookup("nerdctl"); err == nil {
+		t.Fatalf("Expected an error for an unknown compose tool")
+	}
+}
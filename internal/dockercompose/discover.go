@@ -0,0 +1,140 @@
+package dockercompose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mkhuda/dockermi/internal/cache"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// ComposeFile is a compose file found by Discover with the services it defines.
+type ComposeFile struct {
+	Path     string
+	Services map[string]DockermiTypes.Service
+}
+
+// Names returns the service names of the file in sorted order.
+func (f ComposeFile) Names() []string {
+	names := make([]string, 0, len(f.Services))
+	for name := range f.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Cache, when set, keeps parsed compose files between runs. Discover parses
+// every file when it is nil, which is the default for library use.
+var Cache *cache.Cache
+
+// DefaultWorkers is the number of parsers used by FindServices and friends.
+var DefaultWorkers = runtime.NumCPU()
+
+// Discover walks root for .yml files and parses them with a pool of at most
+// workers goroutines (DefaultWorkers when workers < 1). Files are returned in
+// walk order, whichever worker parsed them. The first error in walk order is
+// returned and stops the remaining work, as does cancelling ctx; the files
+// parsed before the failing one are returned with the error.
+func Discover(ctx context.Context, root string, workers int) ([]ComposeFile, error) {
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		index int
+		path  string
+	}
+	type result struct {
+		services map[string]DockermiTypes.Service
+		err      error
+	}
+
+	jobs := make(chan job)
+	results := make(map[int]result)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				services, err := parseFile(j.path)
+				if err != nil {
+					cancel()
+				}
+				mu.Lock()
+				results[j.index] = result{services, err}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var paths []string
+	walkErr := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yml") {
+			return nil
+		}
+
+		select {
+		case jobs <- job{len(paths), path}:
+			paths = append(paths, path)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(jobs)
+	wg.Wait()
+
+	// A parse error explains a cancelled walk, so it is reported first
+	files := make([]ComposeFile, 0, len(paths))
+	for i, path := range paths {
+		r := results[i]
+		if r.err != nil {
+			return files, r.err
+		}
+		files = append(files, ComposeFile{Path: path, Services: r.services})
+	}
+	return files, walkErr
+}
+
+// ComposeFiles returns the paths of the files under root that define services,
+// in walk order.
+func ComposeFiles(root string) ([]string, error) {
+	files, err := Discover(context.Background(), root, DefaultWorkers)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, file := range files {
+		if len(file.Services) > 0 {
+			paths = append(paths, file.Path)
+		}
+	}
+	return paths, nil
+}
+
+// parseFile parses the compose file at path, going through Cache when it is set.
+func parseFile(path string) (map[string]DockermiTypes.Service, error) {
+	if Cache == nil {
+		return ParseComposeFile(path, false, false)
+	}
+	services, _, err := Cache.Load(path, ParseCompose)
+	return services, err
+}
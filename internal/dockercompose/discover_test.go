@@ -0,0 +1,91 @@
+package dockercompose_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+)
+
+func TestDiscover(t *testing.T) {
+	root := fixturesDir(t)
+
+	sequential, err := dockercompose.Discover(context.Background(), root, 1)
+	if err != nil {
+		t.Fatalf("Error discovering compose files: %v", err)
+	}
+	if len(sequential) == 0 {
+		t.Fatalf("Expected compose files in %s", root)
+	}
+
+	// The result must not depend on how many workers parsed the files
+	for i := 0; i < 5; i++ {
+		concurrent, err := dockercompose.Discover(context.Background(), root, 8)
+		if err != nil {
+			t.Fatalf("Error discovering compose files: %v", err)
+		}
+		if len(concurrent) != len(sequential) {
+			t.Fatalf("Expected %d files, got %d", len(sequential), len(concurrent))
+		}
+		for j := range sequential {
+			if concurrent[j].Path != sequential[j].Path || strings.Join(concurrent[j].Names(), ",") != strings.Join(sequential[j].Names(), ",") {
+				t.Fatalf("Expected %s %v at position %d, got %s %v", sequential[j].Path, sequential[j].Names(), j, concurrent[j].Path, concurrent[j].Names())
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dockercompose.Discover(ctx, root, 4); err != context.Canceled {
+		t.Fatalf("Expected a cancelled discovery to fail with %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestFindServicesPartialResults(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "c"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		compose := "services:\n  " + dir + ":\n    image: nginx\n    labels:\n      dockermi.order: \"1\"\n      dockermi.active: \"true\"\n"
+		if err := os.WriteFile(filepath.Join(root, dir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+			t.Fatalf("Error writing compose file: %v", err)
+		}
+	}
+	// b.yml points to a missing file, so it cannot be read
+	if err := os.Symlink(filepath.Join(root, "missing.yml"), filepath.Join(root, "b.yml")); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	// The services found before the unreadable file are returned with its error
+	services, err := dockercompose.FindServices(root, false)
+	if err == nil {
+		t.Fatalf("Expected an error for the unreadable compose file")
+	}
+	if len(services) != 1 || services[0].ServiceName != "a" {
+		t.Fatalf("Expected the services of the files before the unreadable one, got: %+v", services)
+	}
+}
+
+// fixturesDir returns the absolute path of the test/ folder. Other tests change
+// the working directory, so it is resolved from the module root.
+func fixturesDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error getting current working directory: %v", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, "test")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatalf("Could not find the module root")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+
+package dockercompose_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+)
+
+func TestDiscoverCancel(t *testing.T) {
+	root := t.TempDir()
+
+	// a.yml is walked first. It is a named pipe, so the only worker blocks
+	// parsing it until the test writes to it.
+	fifo := filepath.Join(root, "a.yml")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("Named pipes are not supported: %v", err)
+	}
+	const total = 50
+	for i := 0; i < total; i++ {
+		dir := filepath.Join(root, "b", fmt.Sprintf("app%02d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0644); err != nil {
+			t.Fatalf("Error writing compose file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type result struct {
+		files []dockercompose.ComposeFile
+		err   error
+	}
+	done := make(chan result)
+	go func() {
+		files, err := dockercompose.Discover(ctx, root, 1)
+		done <- result{files, err}
+	}()
+
+	// Opening the pipe waits for the worker to open it
+	writer, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Error opening the pipe: %v", err)
+	}
+	cancel()
+	if _, err := writer.WriteString("services:\n  db:\n    image: postgres\n"); err != nil {
+		t.Fatalf("Error writing to the pipe: %v", err)
+	}
+	writer.Close()
+
+	r := <-done
+	if r.err != context.Canceled {
+		t.Fatalf("Expected the discovery to fail with %v, got: %v", context.Canceled, r.err)
+	}
+	// The walk stops at the next file, so at most one more file is parsed
+	if len(r.files) == 0 || len(r.files) > 2 || r.files[0].Path != fifo {
+		t.Fatalf("Expected the pool to stop after the cancellation, got %d of %d files", len(r.files), total+1)
+	}
+}
@@ -1,11 +1,14 @@
 package dockercompose
 
 import (
+	"context"
+	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/groupkey"
 	DockermiTypes "github.com/mkhuda/dockermi/types"
 
 	"gopkg.in/yaml.v2"
@@ -16,6 +19,9 @@ import (
 // It scans the directory and its subdirectories for docker-compose.yml files,
 // parses them to extract services with specific labels, and returns a list of
 // these services along with a boolean indicating if any docker-compose.yml files were found.
+// Files are parsed concurrently by Discover; services are returned in walk order and
+// sorted by name within each file. When a file cannot be parsed, the services of the
+// files before it are returned with the error.
 //
 // Parameters:
 //   - root: the root directory to start the search from
@@ -27,22 +33,15 @@ import (
 func FindServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, error) {
 	var services DockermiTypes.ServiceScriptReturn
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yml") {
-			return nil
-		}
-
-		composedFiles, err := ParseComposeFile(path, false, force)
-
-		if err != nil {
-			return err
-		}
+	// On error the services of the files parsed so far are returned with it
+	files, err := Discover(context.Background(), root, DefaultWorkers)
+	if err != nil {
+		color.Red("Error walking the path: %v", err)
+	}
 
-		for serviceName, service := range composedFiles {
+	for _, file := range files {
+		for _, serviceName := range file.Names() {
+			service := file.Services[serviceName]
 			order, active := "", ""
 			orderExists, activeExists := false, false
 
@@ -69,7 +68,9 @@ func FindServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, e
 				services = append(services, DockermiTypes.ServiceScript{
 					Order:       order,
 					ServiceName: serviceName,
-					ComposeFile: path,
+					ComposeFile: file.Path,
+					Keys:        SplitKeys(service.Labels["dockermi.key"]),
+					Definition:  service,
 				})
 
 			} else if activeExists {
@@ -78,11 +79,6 @@ func FindServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, e
 				color.HiYellowString("Service '%s' is missing 'dockermi.order' or 'dockermi.active' labels. Skipping...", serviceName)
 			}
 		}
-		return nil
-	})
-
-	if err != nil {
-		color.Red("Error walking the path: %v", err)
 	}
 
 	return services, err
@@ -91,11 +87,13 @@ func FindServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, e
 // [Proposed Feature]
 // FindServicesWithKey searches for docker-compose.yml files in the specified directory
 // and groups the services by their 'dockermi.key' label. It parses each file to extract
-// services that are active and have an associated order. If the 'dockermi.key' label is
-// missing, a default key will be generated. The function returns a map where the keys are
-// the values of 'dockermi.key' and the values are slices of ServiceScript structures
-// containing the order, service name, and the compose file path. In case of an error during
-// the file traversal or parsing, it returns the error encountered.
+// services that are active and have an associated order. The 'dockermi.key' label may hold
+// a comma-separated list (or a YAML list) of keys, in which case the service is added to
+// every listed group and marked as shared. Services without a key are skipped, and so
+// are keys that groupkey.Validate rejects. The function
+// returns a map where the keys are the values of 'dockermi.key' and the values are slices
+// of ServiceScript structures containing the order, service name, and the compose file path.
+// In case of an error during the file traversal or parsing, it returns the error encountered.
 //
 // Parameters:
 //   - root: the root directory to start the search from
@@ -107,49 +105,102 @@ func FindServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, e
 func FindServicesWithKey(root string) (map[string][]DockermiTypes.ServiceScript, error) {
 	groups := make(map[string][]DockermiTypes.ServiceScript)
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yml") {
-			return nil
-		}
-
-		services, err := ParseComposeFile(path, true, false)
-
-		if err != nil {
-			return err
-		}
+	// On error the services of the files parsed so far are returned with it
+	files, err := Discover(context.Background(), root, DefaultWorkers)
+	if err != nil {
+		color.Red("Error walking the path: %v", err)
+	}
 
-		for serviceName, service := range services {
+	for _, file := range files {
+		for _, serviceName := range file.Names() {
+			service := file.Services[serviceName]
 			order, orderExists := service.Labels["dockermi.order"]
 			active, activeExists := service.Labels["dockermi.active"]
-			key := service.Labels["dockermi.key"] // Check for dockermi.key
-
-			if key != "" && orderExists && activeExists && active == "true" {
-				if _, exists := groups[key]; !exists {
-					groups[key] = []DockermiTypes.ServiceScript{}
+			keys := validKeys(serviceName, file.Path, SplitKeys(service.Labels["dockermi.key"])) // Check for dockermi.key
+
+			if len(keys) > 0 && orderExists && activeExists && active == "true" {
+				// SplitKeys drops repeated keys, so each service is planned once per group
+				for _, key := range keys {
+					groups[key] = append(groups[key], DockermiTypes.ServiceScript{
+						Order:       order,
+						ServiceName: serviceName,
+						ComposeFile: file.Path,
+						Keys:        keys,
+						Shared:      len(keys) > 1,
+						Definition:  service,
+					})
 				}
-				groups[key] = append(groups[key], DockermiTypes.ServiceScript{
-					Order:       order,
-					ServiceName: serviceName,
-					ComposeFile: path,
-				})
 			} else if activeExists {
 				color.Yellow("Service '%s' is inactive (dockermi.active=false). Skipping...", serviceName)
 			} else {
 				color.HiYellowString("Service '%s' is missing 'dockermi.order' or 'dockermi.active' labels. Skipping...", serviceName)
 			}
 		}
-		return nil
-	})
+	}
+
+	return groups, err
+}
 
+// FindAllServices returns every service defined in the compose files below root,
+// including the ones FindServices would skip. Those are marked as Inactive.
+func FindAllServices(root string, force bool) (DockermiTypes.ServiceScriptReturn, error) {
+	var services DockermiTypes.ServiceScriptReturn
+
+	files, err := Discover(context.Background(), root, DefaultWorkers)
 	if err != nil {
-		color.Red("Error walking the path: %v", err)
+		return nil, err
 	}
 
-	return groups, err
+	for _, file := range files {
+		for _, serviceName := range file.Names() {
+			service := file.Services[serviceName]
+			_, orderExists := service.Labels["dockermi.order"]
+			active, activeExists := service.Labels["dockermi.active"]
+
+			services = append(services, DockermiTypes.ServiceScript{
+				Order:       service.Labels["dockermi.order"],
+				ServiceName: serviceName,
+				ComposeFile: file.Path,
+				Keys:        SplitKeys(service.Labels["dockermi.key"]),
+				Inactive:    !force && !(orderExists && activeExists && active == "true"),
+				Definition:  service,
+			})
+		}
+	}
+
+	return services, nil
+}
+
+// validKeys returns the keys of a service that groupkey.Validate accepts,
+// since keys become part of group script file names. The others are reported
+// and skipped.
+func validKeys(serviceName, composeFile string, keys []string) []string {
+	var valid []string
+	for _, key := range keys {
+		if err := groupkey.Validate(key); err != nil {
+			color.Yellow("Service '%s' in %s: %v. Skipping the key...", serviceName, composeFile, err)
+			continue
+		}
+		valid = append(valid, key)
+	}
+	return valid
+}
+
+// SplitKeys splits a 'dockermi.key' label value into its individual keys.
+// Keys are separated by commas; surrounding whitespace, empty entries and
+// duplicates are dropped while the original order is kept.
+func SplitKeys(value string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
 }
 
 // ParseComposeFile reads and parses a docker-compose.yml file located at the specified path.
@@ -172,9 +223,14 @@ func ParseComposeFile(path string, withKey bool, force bool) (map[string]Dockerm
 	if err != nil {
 		return nil, err
 	}
+	return ParseCompose(file)
+}
 
+// ParseCompose parses the content of a compose file and returns its services
+// by name. Content that is not valid YAML or has no services yields no services.
+func ParseCompose(content []byte) (map[string]DockermiTypes.Service, error) {
 	var composeFile map[string]interface{}
-	err = yaml.Unmarshal(file, &composeFile)
+	err := yaml.Unmarshal(content, &composeFile)
 	if err != nil {
 		return nil, nil
 	}
@@ -213,12 +269,54 @@ func unmarshalService(data map[interface{}]interface{}) (DockermiTypes.Service,
 	}
 	if ports, ok := data["ports"].([]interface{}); ok {
 		for _, port := range ports {
-			if p, ok := port.(string); ok {
+			switch p := port.(type) {
+			case string:
 				service.Ports = append(service.Ports, p)
+			case int:
+				service.Ports = append(service.Ports, fmt.Sprint(p))
 			}
 		}
 	}
 
+	service.Environment = unmarshalEnvironment(data["environment"])
+
+	if volumes, ok := data["volumes"].([]interface{}); ok {
+		for _, volume := range volumes {
+			switch volume := volume.(type) {
+			case string:
+				service.Volumes = append(service.Volumes, volume)
+			case map[interface{}]interface{}:
+				// Long syntax: keep it in the short "source:target" form
+				source, _ := volume["source"].(string)
+				target, _ := volume["target"].(string)
+				if target != "" {
+					service.Volumes = append(service.Volumes, strings.TrimPrefix(source+":"+target, ":"))
+				}
+			}
+		}
+	}
+
+	// depends_on is either a list of service names or a map of name to condition
+	switch dependsOn := data["depends_on"].(type) {
+	case []interface{}:
+		for _, dependency := range dependsOn {
+			if name, ok := dependency.(string); ok {
+				service.DependsOn = append(service.DependsOn, name)
+			}
+		}
+	case map[interface{}]interface{}:
+		for dependency := range dependsOn {
+			if name, ok := dependency.(string); ok {
+				service.DependsOn = append(service.DependsOn, name)
+			}
+		}
+		sort.Strings(service.DependsOn)
+	}
+
+	if healthcheck, ok := data["healthcheck"].(map[interface{}]interface{}); ok {
+		service.Healthcheck = unmarshalHealthcheck(healthcheck)
+	}
+
 	// Handle labels
 	if labels, ok := data["labels"]; ok {
 		switch labels := labels.(type) {
@@ -234,8 +332,18 @@ func unmarshalService(data map[interface{}]interface{}) (DockermiTypes.Service,
 		case map[interface{}]interface{}:
 			for k, v := range labels {
 				if key, ok := k.(string); ok {
-					if value, ok := v.(string); ok {
+					switch value := v.(type) {
+					case string:
 						service.Labels[key] = value
+					case []interface{}:
+						// YAML lists (e.g. dockermi.key: [web, api]) are kept comma-separated
+						var items []string
+						for _, item := range value {
+							if str, ok := item.(string); ok {
+								items = append(items, str)
+							}
+						}
+						service.Labels[key] = strings.Join(items, ",")
 					}
 				}
 			}
@@ -244,3 +352,51 @@ func unmarshalService(data map[interface{}]interface{}) (DockermiTypes.Service,
 
 	return service, nil
 }
+
+// unmarshalEnvironment reads the environment of a service, given either as a
+// list of KEY=VALUE strings or as a map.
+func unmarshalEnvironment(data interface{}) map[string]string {
+	environment := make(map[string]string)
+	switch data := data.(type) {
+	case []interface{}:
+		for _, item := range data {
+			if str, ok := item.(string); ok {
+				parts := strings.SplitN(str, "=", 2)
+				if len(parts) == 2 {
+					environment[parts[0]] = parts[1]
+				} else {
+					environment[parts[0]] = ""
+				}
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, v := range data {
+			if key, ok := k.(string); ok {
+				if v == nil {
+					environment[key] = ""
+				} else {
+					environment[key] = fmt.Sprint(v)
+				}
+			}
+		}
+	}
+	return environment
+}
+
+// unmarshalHealthcheck reads the healthcheck of a service. A test given as a
+// plain string is stored as ["CMD-SHELL", test], like docker-compose does.
+func unmarshalHealthcheck(data map[interface{}]interface{}) *DockermiTypes.Healthcheck {
+	healthcheck := &DockermiTypes.Healthcheck{}
+	switch test := data["test"].(type) {
+	case string:
+		healthcheck.Test = []string{"CMD-SHELL", test}
+	case []interface{}:
+		for _, item := range test {
+			healthcheck.Test = append(healthcheck.Test, fmt.Sprint(item))
+		}
+	}
+	healthcheck.Interval, _ = data["interval"].(string)
+	healthcheck.Timeout, _ = data["timeout"].(string)
+	healthcheck.Retries, _ = data["retries"].(int)
+	return healthcheck
+}
@@ -0,0 +1,57 @@
+package dockercompose_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+)
+
+func TestFindServiceByMultipleKeys(t *testing.T) {
+	projectDir := t.TempDir()
+	compose := `services:
+  postgres:
+    image: postgres:latest
+    labels:
+      dockermi.order: "1"
+      dockermi.active: "true"
+      dockermi.key: "billing, search,billing"
+  redis:
+    image: redis:latest
+    labels:
+      dockermi.order: "1"
+      dockermi.active: "true"
+      dockermi.key: [search, web]
+  api:
+    image: hello-world
+    labels:
+      dockermi.order: "2"
+      dockermi.active: "true"
+      dockermi.key: "billing, ../../escape"
+`
+	if err := os.WriteFile(filepath.Join(projectDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	groupedServices, err := dockercompose.FindServicesWithKey(projectDir)
+	if err != nil {
+		t.Fatalf("Error finding services: %v", err)
+	}
+
+	expected := map[string]int{"billing": 2, "search": 2, "web": 1}
+	if len(groupedServices) != len(expected) {
+		t.Fatalf("Expected groups %v, got: %v", expected, groupedServices)
+	}
+	for key, count := range expected {
+		if len(groupedServices[key]) != count {
+			t.Fatalf("Expected %v services in group %v, got: %v", count, key, groupedServices[key])
+		}
+	}
+
+	for _, service := range groupedServices["billing"] {
+		if shared := service.ServiceName == "postgres"; service.Shared != shared {
+			t.Fatalf("Expected %v shared=%v", service.ServiceName, shared)
+		}
+	}
+}
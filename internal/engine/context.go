@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+)
+
+// Connect returns a client for the engine selected by target: the endpoint of
+// its docker context or its host. The zero target uses FromEnv.
+func Connect(target compose.Target) (*Client, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case target.Host != "":
+		return NewClient(target.Host)
+	case target.Context != "":
+		host, err := ContextHost(target.Context)
+		if err != nil {
+			return nil, err
+		}
+		return NewClient(host)
+	default:
+		return FromEnv()
+	}
+}
+
+// CurrentContext returns the docker context selected by DOCKER_CONTEXT, or
+// else the current context of the docker CLI configuration, or "" when none is.
+func CurrentContext() string {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if json.Unmarshal(data, &config) != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// ContextHost returns the engine address of the docker context name, read
+// from the context store of the docker CLI. The "default" context is the
+// engine found by DiscoverHost.
+func ContextHost(name string) (string, error) {
+	if name == "default" {
+		return DiscoverHost(), nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	// Contexts are stored under the SHA-256 digest of their name
+	path := filepath.Join(dir, "contexts", "meta", fmt.Sprintf("%x", sha256.Sum256([]byte(name))), "meta.json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("docker context not found: %s", name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", fmt.Errorf("invalid docker context %s: %w", name, err)
+	}
+	host := meta.Endpoints["docker"].Host
+	if host == "" {
+		return "", fmt.Errorf("docker context %s has no docker endpoint", name)
+	}
+	return host, nil
+}
+
+// configDir returns the docker CLI configuration directory: DOCKER_CONFIG or ~/.docker.
+func configDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker"), nil
+}
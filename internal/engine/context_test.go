@@ -0,0 +1,84 @@
+package engine_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/engine"
+)
+
+func TestConnect(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(fakeEngine(t))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	// Docker contexts are stored under the SHA-256 digest of their name
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	metaDir := filepath.Join(configDir, "contexts", "meta", fmt.Sprintf("%x", sha256.Sum256([]byte("build-box"))))
+	if err := os.MkdirAll(metaDir, 0755); err != nil {
+		t.Fatalf("Error creating context store: %v", err)
+	}
+	meta := `{"Name":"build-box","Metadata":{},"Endpoints":{"docker":{"Host":"unix://` + socket + `","SkipTLSVerify":false}}}`
+	if err := os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0644); err != nil {
+		t.Fatalf("Error writing context: %v", err)
+	}
+
+	client, err := engine.Connect(compose.Target{Context: "build-box"})
+	if err != nil || client.Host() != "unix://"+socket {
+		t.Fatalf("Expected the endpoint of the build-box context, got: %v (%v)", client, err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Error pinging the context engine: %v", err)
+	}
+	if _, err := engine.Connect(compose.Target{Context: "missing"}); err == nil || !strings.Contains(err.Error(), "docker context not found: missing") {
+		t.Fatalf("Expected a missing context error, got: %v", err)
+	}
+	if _, err := engine.Connect(compose.Target{Context: "build-box", Host: "tcp://build:2375"}); err == nil {
+		t.Fatalf("Expected an error for both a context and a host")
+	}
+
+	// The current context of the docker CLI is used when nothing is selected
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"currentContext":"build-box"}`), 0644); err != nil {
+		t.Fatalf("Error writing docker config: %v", err)
+	}
+	if client, err := engine.FromEnv(); err != nil || client.Host() != "unix://"+socket {
+		t.Fatalf("Expected the current context, got: %v (%v)", client, err)
+	}
+
+	// ssh:// hosts tunnel HTTP through 'docker system dial-stdio'
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "ssh.log")
+	fakeSSH := "#!/bin/sh\necho \"$@\" > \"" + logPath + "\"\nread request\n" +
+		"printf 'HTTP/1.1 200 OK\\r\\nContent-Length: 2\\r\\n\\r\\nOK'\ncat > /dev/null\n"
+	if err := os.WriteFile(filepath.Join(binDir, "ssh"), []byte(fakeSSH), 0755); err != nil {
+		t.Fatalf("Error writing fake ssh: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	client, err = engine.Connect(compose.Target{Host: "ssh://deploy@build:2222"})
+	if err != nil {
+		t.Fatalf("Error creating ssh client: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Error pinging over ssh: %v", err)
+	}
+	if log, _ := os.ReadFile(logPath); string(log) != "-l deploy -p 2222 -- build docker system dial-stdio\n" {
+		t.Fatalf("Unexpected ssh arguments: %q", log)
+	}
+}
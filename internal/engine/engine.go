@@ -0,0 +1,306 @@
+// Package engine is a small client for the Docker Engine HTTP API, covering the
+// container state dockermi needs without parsing compose CLI output.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+)
+
+// DefaultHost is the engine address used when no host is configured and no
+// engine socket is found.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Labels set by docker compose on the containers it creates.
+const (
+	ProjectLabel = "com.docker.compose.project"
+	ServiceLabel = "com.docker.compose.service"
+)
+
+// Client talks to a Docker Engine (or a compatible API such as Podman's).
+type Client struct {
+	host    string
+	baseURL string
+	http    *http.Client
+}
+
+// Container is a container as listed by the engine.
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// Project returns the compose project the container belongs to.
+func (c Container) Project() string {
+	return c.Labels[ProjectLabel]
+}
+
+// Service returns the compose service the container runs.
+func (c Container) Service() string {
+	return c.Labels[ServiceLabel]
+}
+
+// State is the state of a container as reported by inspect.
+type State struct {
+	Status   string  `json:"Status"`
+	Running  bool    `json:"Running"`
+	ExitCode int     `json:"ExitCode"`
+	Health   *Health `json:"Health"`
+}
+
+// HealthStatus returns the healthcheck status ("starting", "healthy" or
+// "unhealthy"), or an empty string when the container has no healthcheck.
+func (s State) HealthStatus() string {
+	if s.Health == nil {
+		return ""
+	}
+	return s.Health.Status
+}
+
+// Health is the healthcheck state of a container.
+type Health struct {
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+}
+
+// Event is a message of the engine event stream.
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time int64 `json:"time"`
+}
+
+// Filters narrow down lists and event streams, e.g. {"label": {"a=b"}}.
+type Filters map[string][]string
+
+// ComposeFilters returns the filters selecting the containers of a compose
+// project, and of one of its services when service is not empty.
+func ComposeFilters(project, service string) Filters {
+	labels := []string{ProjectLabel + "=" + project}
+	if service != "" {
+		labels = append(labels, ServiceLabel+"="+service)
+	}
+	return Filters{"label": labels}
+}
+
+// FromEnv returns a client for the engine the docker CLI would use:
+// DOCKER_HOST, then the docker context selected by DOCKER_CONTEXT or the
+// docker CLI configuration, and otherwise the engine found by DiscoverHost.
+func FromEnv() (*Client, error) {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return NewClient(host)
+	}
+	if name := CurrentContext(); name != "" && name != "default" {
+		return Connect(compose.Target{Context: name})
+	}
+	return NewClient(DiscoverHost())
+}
+
+// DiscoverHost returns DOCKER_HOST, or else CONTAINER_HOST (used by Podman),
+// or else the first engine socket of Sockets that exists, or DefaultHost.
+func DiscoverHost() string {
+	for _, name := range []string{"DOCKER_HOST", "CONTAINER_HOST"} {
+		if host := os.Getenv(name); host != "" {
+			return host
+		}
+	}
+	for _, socket := range Sockets() {
+		if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return "unix://" + socket
+		}
+	}
+	return DefaultHost
+}
+
+// Sockets returns the paths where engine sockets are looked for: the Docker
+// socket, then the rootless Podman socket in $XDG_RUNTIME_DIR, then the
+// rootful Podman socket.
+func Sockets() []string {
+	sockets := []string{"/var/run/docker.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		sockets = append(sockets, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	return append(sockets, "/run/podman/podman.sock")
+}
+
+// NewClient returns a client for an engine listening on host, given as
+// unix:///path/to/socket, tcp://address:port or ssh://[user@]host[:port].
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine host %q: %w", host, err)
+	}
+
+	c := &Client{host: host}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.baseURL = "http://engine"
+		c.http = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+	case "tcp", "http":
+		c.baseURL = "http://" + u.Host
+		c.http = &http.Client{}
+	case "ssh":
+		c.baseURL = "http://engine"
+		c.http = &http.Client{Transport: &http.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) {
+				return dialSSH(u)
+			},
+		}}
+	default:
+		return nil, fmt.Errorf("unsupported engine host %q (supported: unix://, tcp://, ssh://)", host)
+	}
+	return c, nil
+}
+
+// Host returns the address the client talks to.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Ping checks that the engine is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.get(ctx, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Containers lists the containers matching filters, including stopped ones.
+func (c *Client) Containers(ctx context.Context, filters Filters) ([]Container, error) {
+	query, err := filterQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("all", "1")
+
+	resp, err := c.get(ctx, "/containers/json", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var containers []Container
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("invalid container list: %w", err)
+	}
+	return containers, nil
+}
+
+// Inspect returns the state of the container with the given ID or name.
+func (c *Client) Inspect(ctx context.Context, id string) (State, error) {
+	resp, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/json", nil)
+	if err != nil {
+		return State{}, err
+	}
+	defer resp.Body.Close()
+
+	var container struct {
+		State State `json:"State"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return State{}, fmt.Errorf("invalid container %s: %w", id, err)
+	}
+	return container.State, nil
+}
+
+// Events streams the engine events matching filters to fn until ctx is
+// cancelled, the engine closes the stream, or fn returns false.
+func (c *Client) Events(ctx context.Context, filters Filters, fn func(Event) bool) error {
+	query, err := filterQuery(filters)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.get(ctx, "/events", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("invalid event: %w", err)
+		}
+		if !fn(event) {
+			return nil
+		}
+	}
+}
+
+// get sends a GET request and returns the response when it succeeded.
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// The request URL is internal, only the underlying error is of interest
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("cannot reach the engine at %s: %w", c.host, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var message struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(body, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(body))
+		}
+		return nil, fmt.Errorf("engine: %s %s: %s (%d)", req.Method, path, message.Message, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// filterQuery encodes filters as the filters query parameter.
+func filterQuery(filters Filters) (url.Values, error) {
+	query := url.Values{}
+	if len(filters) == 0 {
+		return query, nil
+	}
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("filters", string(encoded))
+	return query, nil
+}
@@ -0,0 +1,165 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/engine"
+)
+
+// fakeEngine serves the parts of the Docker Engine API used by dockermi.
+func fakeEngine(t *testing.T) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil || r.URL.Query().Get("all") != "1" {
+			http.Error(w, `{"message":"bad filters"}`, http.StatusBadRequest)
+			return
+		}
+		if strings.Join(filters["label"], ",") != "com.docker.compose.project=shop,com.docker.compose.service=db" {
+			fmt.Fprint(w, "[]")
+			return
+		}
+		fmt.Fprint(w, `[{"Id":"abc123","Names":["/shop-db-1"],"Image":"postgres","State":"running","Status":"Up 2 minutes","Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"db"}}]`)
+	})
+	mux.HandleFunc("/containers/abc123/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id":"abc123","State":{"Status":"running","Running":true,"Health":{"Status":"healthy","FailingStreak":0}}}`)
+	})
+	mux.HandleFunc("/containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"No such container: missing"}`)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		for _, action := range []string{"start", "health_status: healthy", "stop"} {
+			fmt.Fprintf(w, `{"Type":"container","Action":%q,"Actor":{"ID":"abc123","Attributes":{"com.docker.compose.service":"db"}},"time":1700000000}`+"\n", action)
+			w.(http.Flusher).Flush()
+		}
+	})
+	return mux
+}
+
+func TestEngineClient(t *testing.T) {
+	// The engine usually listens on a unix socket
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	unixServer := httptest.NewUnstartedServer(fakeEngine(t))
+	unixServer.Listener = listener
+	unixServer.Start()
+	defer unixServer.Close()
+
+	tcpServer := httptest.NewServer(fakeEngine(t))
+	defer tcpServer.Close()
+
+	for _, host := range []string{"unix://" + socket, strings.Replace(tcpServer.URL, "http://", "tcp://", 1)} {
+		t.Setenv("DOCKER_HOST", host)
+		client, err := engine.FromEnv()
+		if err != nil {
+			t.Fatalf("Error creating client for %s: %v", host, err)
+		}
+		ctx := context.Background()
+
+		if err := client.Ping(ctx); err != nil {
+			t.Fatalf("Error pinging %s: %v", host, err)
+		}
+
+		containers, err := client.Containers(ctx, engine.ComposeFilters("shop", "db"))
+		if err != nil || len(containers) != 1 {
+			t.Fatalf("Expected one container from %s, got: %+v (%v)", host, containers, err)
+		}
+		if containers[0].Project() != "shop" || containers[0].Service() != "db" || containers[0].State != "running" {
+			t.Fatalf("Unexpected container: %+v", containers[0])
+		}
+
+		state, err := client.Inspect(ctx, containers[0].ID)
+		if err != nil || !state.Running || state.HealthStatus() != "healthy" {
+			t.Fatalf("Expected a running healthy container, got: %+v (%v)", state, err)
+		}
+
+		if _, err := client.Inspect(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "No such container: missing (404)") {
+			t.Fatalf("Expected the engine error message, got: %v", err)
+		}
+
+		var actions []string
+		err = client.Events(ctx, engine.ComposeFilters("shop", ""), func(event engine.Event) bool {
+			actions = append(actions, event.Action)
+			return event.Action != "health_status: healthy"
+		})
+		if err != nil || strings.Join(actions, ",") != "start,health_status: healthy" {
+			t.Fatalf("Expected events up to the healthy one, got: %v (%v)", actions, err)
+		}
+	}
+
+	if _, err := engine.NewClient("npipe:////./pipe/docker_engine"); err == nil {
+		t.Fatalf("Expected an error for an unsupported host")
+	}
+	client, err := engine.NewClient("unix://" + filepath.Join(t.TempDir(), "missing.sock"))
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	if err := client.Ping(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "cannot reach the engine") {
+		t.Fatalf("Expected an unreachable engine error, got: %v", err)
+	}
+}
+
+func TestWaitHealthy(t *testing.T) {
+	server := httptest.NewServer(fakeEngine(t))
+	defer server.Close()
+	client, err := engine.NewClient(strings.Replace(server.URL, "http://", "tcp://", 1))
+	if err != nil {
+		t.Fatalf("Error creating client: %v", err)
+	}
+	if health, err := client.WaitHealthy(context.Background(), "shop", "db", time.Millisecond); err != nil || health != "healthy" {
+		t.Fatalf("Expected db to be healthy, got: %q (%v)", health, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitHealthy(ctx, "shop", "web", time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a timeout for a service without containers, got: %v", err)
+	}
+}
+
+func TestDiscoverPodmanSocket(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("CONTAINER_HOST", "unix:///run/user/1000/podman/podman.sock")
+	if host := engine.DiscoverHost(); host != "unix:///run/user/1000/podman/podman.sock" {
+		t.Fatalf("Expected CONTAINER_HOST, got: %s", host)
+	}
+	t.Setenv("CONTAINER_HOST", "")
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+	if err := os.MkdirAll(filepath.Dir(socket), 0755); err != nil {
+		t.Fatalf("Error creating socket directory: %v", err)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	defer listener.Close()
+
+	expected := "unix://" + socket
+	// A Docker socket takes precedence over Podman's
+	if info, err := os.Stat("/var/run/docker.sock"); err == nil && info.Mode()&os.ModeSocket != 0 {
+		expected = engine.DefaultHost
+	}
+	if host := engine.DiscoverHost(); host != expected {
+		t.Fatalf("Expected %s, got: %s", expected, host)
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitHealthy polls the containers of service in the compose project every
+// interval until they are all running and, when they have a healthcheck,
+// healthy. It returns "healthy", or "running" when no container has a
+// healthcheck. It fails when a container exits or becomes unhealthy, or when
+// ctx is done first.
+func (c *Client) WaitHealthy(ctx context.Context, project, service string, interval time.Duration) (string, error) {
+	health := "not created"
+	for {
+		current, done, err := c.serviceHealth(ctx, project, service)
+		// The deadline may expire in the middle of a request
+		if ctx.Err() == nil && (err != nil || done) {
+			return current, err
+		}
+		if current != "" {
+			health = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for %s to become healthy (%s)", service, health)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// serviceHealth returns the health of the containers of service and whether
+// waiting is over.
+func (c *Client) serviceHealth(ctx context.Context, project, service string) (string, bool, error) {
+	containers, err := c.Containers(ctx, ComposeFilters(project, service))
+	if err != nil || len(containers) == 0 {
+		return "", false, err
+	}
+
+	health := "running"
+	for _, container := range containers {
+		state, err := c.Inspect(ctx, container.ID)
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case state.Status == "exited" || state.Status == "dead":
+			return state.Status, false, fmt.Errorf("container %s %s with code %d", container.ID, state.Status, state.ExitCode)
+		case state.HealthStatus() == "unhealthy":
+			return "unhealthy", false, fmt.Errorf("container %s is unhealthy", container.ID)
+		case state.HealthStatus() == "starting":
+			return "starting", false, nil
+		case !state.Running:
+			return state.Status, false, nil
+		case state.HealthStatus() == "healthy":
+			health = "healthy"
+		}
+	}
+	return health, true, nil
+}
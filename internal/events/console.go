@@ -0,0 +1,54 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Console prints events as the colored progress messages of dockermi.
+type Console struct{}
+
+// Event prints event.
+func (Console) Event(event Event) {
+	switch event.Type {
+	case PlanResolved:
+		services := 0
+		for _, phase := range event.Phases {
+			services += len(phase.Services)
+		}
+		color.Cyan("Plan: %d service(s) in %d phase(s)", services, len(event.Phases))
+	case ServiceStarting:
+		if event.Action == "down" {
+			color.Yellow("Stopping %s...", event.Service)
+		} else {
+			color.Green("Starting %s...", event.Service)
+		}
+	case ServiceHealthy:
+		color.Green("%s is %s (%s)", event.Service, event.Health, round(event.Duration))
+	case ServiceFailed:
+		color.Red("%s failed after %s: %s", event.Service, round(event.Duration), event.Error)
+	case PhaseComplete:
+		color.Blue("Phase %s complete (%s)", PhaseName(event.Phase), round(event.Duration))
+	case RunFinished:
+		if event.Failed() {
+			color.Red("%s failed after %s: %s", event.Action, round(event.Duration), event.Error)
+		} else {
+			color.Green("%s finished in %s", event.Action, round(event.Duration))
+		}
+	}
+}
+
+// PhaseName returns how a phase is shown: its order, or "-" for services without one.
+func PhaseName(order string) string {
+	if order == "" {
+		return "-"
+	}
+	return order
+}
+
+// round shortens d for display.
+func round(d time.Duration) string {
+	return fmt.Sprint(d.Round(10 * time.Millisecond))
+}
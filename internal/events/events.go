@@ -0,0 +1,102 @@
+// Package events describes what happens during an up or down run as typed
+// events, and provides sinks writing them to the console, to JSON lines and to
+// JUnit XML reports.
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Type is the kind of an event.
+type Type string
+
+// Event types, in the order they occur during a run.
+const (
+	// PlanResolved is sent once before anything runs, with the phases of the run.
+	PlanResolved Type = "plan-resolved"
+	// ServiceStarting is sent before the compose command of a service runs.
+	ServiceStarting Type = "service-starting"
+	// ServiceHealthy is sent when a started service is running and, when it
+	// has a healthcheck, healthy. On down runs it is sent once the service stopped.
+	ServiceHealthy Type = "service-healthy"
+	// ServiceFailed is sent when the compose command of a service fails or the
+	// service does not become healthy. The run stops after it.
+	ServiceFailed Type = "service-failed"
+	// PhaseComplete is sent when every service of a dockermi.order phase is done.
+	PhaseComplete Type = "phase-complete"
+	// RunFinished is the last event of a run, with its outcome.
+	RunFinished Type = "run-finished"
+)
+
+// Event is something that happened during a run.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Action is the dockermi command being run, "up" or "down".
+	Action string `json:"action"`
+	// Service and ComposeFile identify the service of service events.
+	Service     string `json:"service,omitempty"`
+	ComposeFile string `json:"compose_file,omitempty"`
+	// Phase is the dockermi.order of the service or phase.
+	Phase string `json:"phase,omitempty"`
+	// Health is the state a service reached: "healthy", "running" when it has
+	// no healthcheck, "started" when it was not checked, or "stopped".
+	Health string `json:"health,omitempty"`
+	// Duration is the time the service, phase or run took.
+	Duration time.Duration `json:"-"`
+	// Command is the time the compose command of a service took; the rest of
+	// its Duration was spent waiting for it to become healthy.
+	Command time.Duration `json:"-"`
+	// Error describes why a service or the run failed.
+	Error string `json:"error,omitempty"`
+	// Phases is the plan of a PlanResolved event.
+	Phases []Phase `json:"phases,omitempty"`
+}
+
+// Phase is a phase of the plan: services sharing a dockermi.order.
+type Phase struct {
+	Order    string    `json:"order"`
+	Services []Service `json:"services"`
+}
+
+// Service is a service of the plan.
+type Service struct {
+	Service     string `json:"service"`
+	ComposeFile string `json:"compose_file"`
+}
+
+// id identifies the service of a service event.
+func (e Event) id() string {
+	return e.ComposeFile + "#" + e.Service
+}
+
+// MarshalJSON encodes the event with its durations in seconds.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		event
+		Duration float64 `json:"duration,omitempty"`
+		Command  float64 `json:"command,omitempty"`
+	}{event(e), e.Duration.Seconds(), e.Command.Seconds()})
+}
+
+// Failed reports whether the event reports a failure.
+func (e Event) Failed() bool {
+	return e.Error != ""
+}
+
+// Observer receives the events of a run.
+type Observer interface {
+	Event(event Event)
+}
+
+// Multi sends every event to each of its observers in turn.
+type Multi []Observer
+
+// Event forwards event to the observers.
+func (m Multi) Event(event Event) {
+	for _, observer := range m {
+		observer.Event(event)
+	}
+}
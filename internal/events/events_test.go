@@ -0,0 +1,117 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/events"
+)
+
+func TestSinks(t *testing.T) {
+	file := "/app/docker-compose.yml"
+	plan := events.Event{Type: events.PlanResolved, Action: "up", Phases: []events.Phase{
+		{Order: "1", Services: []events.Service{{Service: "db", ComposeFile: file}}},
+		{Order: "2", Services: []events.Service{{Service: "api", ComposeFile: file}, {Service: "web", ComposeFile: file}}},
+		{Order: "3", Services: []events.Service{{Service: "worker", ComposeFile: file}}},
+	}}
+	run := []events.Event{
+		plan,
+		{Type: events.ServiceStarting, Service: "db", ComposeFile: file, Phase: "1"},
+		{Type: events.ServiceHealthy, Service: "db", ComposeFile: file, Phase: "1", Health: "healthy", Duration: 1500 * time.Millisecond, Command: time.Second},
+		{Type: events.PhaseComplete, Phase: "1", Duration: 1500 * time.Millisecond},
+		{Type: events.ServiceStarting, Service: "api", ComposeFile: file, Phase: "2"},
+		{Type: events.ServiceHealthy, Service: "api", ComposeFile: file, Phase: "2", Health: "healthy"},
+		{Type: events.ServiceStarting, Service: "web", ComposeFile: file, Phase: "2"},
+		{Type: events.ServiceFailed, Service: "web", ComposeFile: file, Phase: "2", Error: "exit status 3"},
+		{Type: events.RunFinished, Action: "up", Error: "web: exit status 3", Duration: 2 * time.Second},
+	}
+
+	var jsonl, junit bytes.Buffer
+	lines, report := events.NewJSONLines(&jsonl), events.NewJUnit()
+	for _, event := range run {
+		events.Multi{lines, report}.Event(event)
+	}
+	if lines.Err != nil {
+		t.Fatalf("Error writing events: %v", lines.Err)
+	}
+	if err := report.Write(&junit); err != nil {
+		t.Fatalf("Error writing the JUnit report: %v", err)
+	}
+
+	written := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(written) != len(run) {
+		t.Fatalf("Expected %d JSON lines, got:\n%s", len(run), jsonl.String())
+	}
+	var healthy, failed map[string]interface{}
+	if err := json.Unmarshal([]byte(written[2]), &healthy); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+	if healthy["duration"] != 1.5 || healthy["command"] != 1.0 {
+		t.Fatalf("Expected durations in seconds, got: %v", healthy)
+	}
+	if err := json.Unmarshal([]byte(written[7]), &failed); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+	if failed["type"] != "service-failed" || failed["service"] != "web" || failed["phase"] != "2" || failed["error"] == nil {
+		t.Fatalf("Unexpected service-failed event: %v", failed)
+	}
+
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name    string    `xml:"name,attr"`
+				Failure *struct{} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil {
+		t.Fatalf("Invalid JUnit report: %v\n%s", err, junit.String())
+	}
+	if suites.Tests != 4 || suites.Failures != 1 || suites.Skipped != 1 || len(suites.Suites) != 3 ||
+		suites.Suites[1].Name != "dockermi up: order 2" || suites.Suites[1].Cases[1].Failure == nil {
+		t.Fatalf("Unexpected JUnit report:\n%s", junit.String())
+	}
+}
+
+func TestJUnitRuns(t *testing.T) {
+	file := "/app/docker-compose.yml"
+	report := events.NewJUnit()
+	for _, action := range []string{"down", "up"} {
+		for _, event := range []events.Event{
+			{Type: events.PlanResolved, Action: action, Phases: []events.Phase{{Order: "1", Services: []events.Service{{Service: "db", ComposeFile: file}}}}},
+			{Type: events.ServiceHealthy, Service: "db", ComposeFile: file, Phase: "1", Duration: time.Second},
+			{Type: events.RunFinished, Action: action, Duration: time.Second},
+		} {
+			report.Event(event)
+		}
+	}
+
+	// The runs of a restart are reported together
+	var junit bytes.Buffer
+	if err := report.Write(&junit); err != nil {
+		t.Fatalf("Error writing the JUnit report: %v", err)
+	}
+	var suites struct {
+		Name   string `xml:"name,attr"`
+		Tests  int    `xml:"tests,attr"`
+		Time   string `xml:"time,attr"`
+		Suites []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil {
+		t.Fatalf("Invalid JUnit report: %v\n%s", err, junit.String())
+	}
+	if suites.Name != "dockermi down, up" || suites.Tests != 2 || suites.Time != "2.000" || len(suites.Suites) != 2 ||
+		suites.Suites[0].Name != "dockermi down: order 1" || suites.Suites[1].Name != "dockermi up: order 1" {
+		t.Fatalf("Unexpected JUnit report:\n%s", junit.String())
+	}
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONLines writes every event as one line of JSON.
+type JSONLines struct {
+	w io.Writer
+	// Err is the first error writing an event; later events are dropped.
+	Err error
+}
+
+// NewJSONLines returns a sink writing JSON lines to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Event writes event.
+func (j *JSONLines) Event(event Event) {
+	if j.Err != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err == nil {
+		_, err = j.w.Write(append(data, '\n'))
+	}
+	j.Err = err
+}
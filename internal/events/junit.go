@@ -0,0 +1,132 @@
+package events
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JUnit collects the events of one or more runs, e.g. the down and up runs
+// of a restart, and writes them as a single JUnit XML report: one test suite
+// per phase of each run and one test case per service. Services a run did not
+// reach are reported as skipped.
+type JUnit struct {
+	report   junitTestSuites
+	elapsed  time.Duration
+	action   string
+	phases   []Phase
+	services map[string]Event         // compose file#service -> last event of the service
+	phaseEnd map[string]time.Duration // phase -> duration
+}
+
+// NewJUnit returns a sink collecting a JUnit report.
+func NewJUnit() *JUnit {
+	return &JUnit{services: make(map[string]Event), phaseEnd: make(map[string]time.Duration)}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Event records event, adding the suites of a run to the report when it
+// finishes.
+func (j *JUnit) Event(event Event) {
+	switch event.Type {
+	case PlanResolved:
+		j.action = event.Action
+		j.phases = event.Phases
+		j.services = make(map[string]Event)
+		j.phaseEnd = make(map[string]time.Duration)
+	case ServiceStarting, ServiceHealthy, ServiceFailed:
+		j.services[event.id()] = event
+	case PhaseComplete:
+		j.phaseEnd[event.Phase] = event.Duration
+	case RunFinished:
+		j.finish(event)
+	}
+}
+
+// finish adds the suites of the run ended by finished to the report.
+func (j *JUnit) finish(finished Event) {
+	report := &j.report
+	if report.Name == "" {
+		report.Name = "dockermi " + j.action
+	} else {
+		report.Name += ", " + j.action
+	}
+	j.elapsed += finished.Duration
+	report.Time = seconds(j.elapsed)
+	for _, phase := range j.phases {
+		suite := junitTestSuite{Name: fmt.Sprintf("dockermi %s: order %s", j.action, PhaseName(phase.Order))}
+		var elapsed time.Duration
+		for _, service := range phase.Services {
+			event, ok := j.services[Event{Service: service.Service, ComposeFile: service.ComposeFile}.id()]
+			testCase := junitTestCase{ClassName: service.ComposeFile, Name: service.Service, Time: seconds(event.Duration)}
+			switch {
+			case !ok || event.Type == ServiceStarting:
+				// Not reached, or interrupted before it finished
+				testCase.Skipped = &struct{}{}
+				suite.Skipped++
+			case event.Type == ServiceFailed:
+				testCase.Failure = &junitFailure{Message: event.Error, Text: event.Error}
+				suite.Failures++
+			}
+			elapsed += event.Duration
+			suite.Tests++
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		if d, ok := j.phaseEnd[phase.Order]; ok {
+			elapsed = d
+		}
+		suite.Time = seconds(elapsed)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+}
+
+// Write writes the report of the runs finished so far to w.
+func (j *JUnit) Write(w io.Writer) error {
+	data, err := xml.MarshalIndent(j.report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// seconds formats d in seconds, as JUnit reports do.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
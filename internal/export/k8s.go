@@ -0,0 +1,381 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/script"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+	"gopkg.in/yaml.v2"
+)
+
+// KubernetesOptions controls the generated Kubernetes manifests.
+type KubernetesOptions struct {
+	// NamespaceByKey places every service in a namespace named after its first
+	// dockermi.key instead of only labelling it with its keys.
+	NamespaceByKey bool
+	// WaitImage is the image used by the init containers waiting for earlier services.
+	WaitImage string
+}
+
+// Minimal Kubernetes object model, limited to the fields dockermi generates.
+type (
+	k8sObject struct {
+		APIVersion string      `yaml:"apiVersion"`
+		Kind       string      `yaml:"kind"`
+		Metadata   k8sMetadata `yaml:"metadata"`
+		Spec       interface{} `yaml:"spec,omitempty"`
+	}
+	k8sMetadata struct {
+		Name        string            `yaml:"name,omitempty"`
+		Namespace   string            `yaml:"namespace,omitempty"`
+		Labels      map[string]string `yaml:"labels,omitempty"`
+		Annotations map[string]string `yaml:"annotations,omitempty"`
+	}
+	k8sDeploymentSpec struct {
+		Replicas int            `yaml:"replicas"`
+		Selector k8sSelector    `yaml:"selector"`
+		Template k8sPodTemplate `yaml:"template"`
+	}
+	k8sSelector struct {
+		MatchLabels map[string]string `yaml:"matchLabels"`
+	}
+	k8sPodTemplate struct {
+		Metadata k8sMetadata `yaml:"metadata"`
+		Spec     k8sPodSpec  `yaml:"spec"`
+	}
+	k8sPodSpec struct {
+		InitContainers []k8sContainer `yaml:"initContainers,omitempty"`
+		Containers     []k8sContainer `yaml:"containers"`
+		Volumes        []k8sVolume    `yaml:"volumes,omitempty"`
+	}
+	k8sContainer struct {
+		Name           string           `yaml:"name"`
+		Image          string           `yaml:"image"`
+		Command        []string         `yaml:"command,omitempty"`
+		Env            []k8sEnv         `yaml:"env,omitempty"`
+		Ports          []k8sPort        `yaml:"ports,omitempty"`
+		VolumeMounts   []k8sVolumeMount `yaml:"volumeMounts,omitempty"`
+		ReadinessProbe *k8sProbe        `yaml:"readinessProbe,omitempty"`
+	}
+	k8sEnv struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	}
+	k8sPort struct {
+		Name          string `yaml:"name,omitempty"`
+		ContainerPort int    `yaml:"containerPort,omitempty"`
+		Port          int    `yaml:"port,omitempty"`
+		TargetPort    int    `yaml:"targetPort,omitempty"`
+		Protocol      string `yaml:"protocol,omitempty"`
+	}
+	k8sVolumeMount struct {
+		Name      string `yaml:"name"`
+		MountPath string `yaml:"mountPath"`
+		ReadOnly  bool   `yaml:"readOnly,omitempty"`
+	}
+	k8sVolume struct {
+		Name                  string            `yaml:"name"`
+		HostPath              map[string]string `yaml:"hostPath,omitempty"`
+		PersistentVolumeClaim map[string]string `yaml:"persistentVolumeClaim,omitempty"`
+	}
+	k8sProbe struct {
+		Exec             map[string][]string `yaml:"exec"`
+		PeriodSeconds    int                 `yaml:"periodSeconds,omitempty"`
+		TimeoutSeconds   int                 `yaml:"timeoutSeconds,omitempty"`
+		FailureThreshold int                 `yaml:"failureThreshold,omitempty"`
+	}
+	k8sServiceSpec struct {
+		Selector map[string]string `yaml:"selector"`
+		Ports    []k8sPort         `yaml:"ports"`
+	}
+	k8sClaimSpec struct {
+		AccessModes []string                     `yaml:"accessModes"`
+		Resources   map[string]map[string]string `yaml:"resources"`
+	}
+)
+
+// Kubernetes writes Deployments, Services, PersistentVolumeClaims and, with
+// NamespaceByKey, Namespaces for the services of data as a multi-document YAML
+// stream. dockermi.order is expressed with init containers that wait until the
+// services of the previous order accept connections on their ports. With
+// NamespaceByKey, a service in a namespace depending on a service without a
+// dockermi.key is an error.
+func Kubernetes(w io.Writer, data script.Data, opts KubernetesOptions) error {
+	if opts.WaitImage == "" {
+		opts.WaitImage = "busybox:1.36"
+	}
+
+	services := make(map[string]script.Service, len(data.Services))
+	for _, service := range data.Services {
+		services[service.Target] = service
+	}
+
+	var objects []k8sObject
+
+	if opts.NamespaceByKey {
+		for _, group := range data.Groups {
+			objects = append(objects, k8sObject{
+				APIVersion: "v1",
+				Kind:       "Namespace",
+				Metadata:   k8sMetadata{Name: k8sName(group.Target)},
+			})
+		}
+	}
+
+	claims := make(map[string]bool)
+	for _, service := range data.Services {
+		name := k8sName(service.Target)
+		namespace := namespaceOf(service, opts)
+		labels := map[string]string{"app.kubernetes.io/name": name, "app.kubernetes.io/managed-by": "dockermi"}
+		for _, key := range service.Keys {
+			labels["dockermi.key/"+k8sName(key)] = "true"
+		}
+
+		container := k8sContainer{
+			Name:           name,
+			Image:          service.Definition.Image,
+			Env:            k8sEnvironment(service.Definition.Environment),
+			ReadinessProbe: k8sReadinessProbe(service.Definition.Healthcheck),
+		}
+		for _, port := range containerPorts(service.Definition.Ports) {
+			container.Ports = append(container.Ports, k8sPort{ContainerPort: port.number, Protocol: port.protocol})
+		}
+
+		pod := k8sPodSpec{}
+		for i, volume := range service.Definition.Volumes {
+			mount, source, readOnly := splitVolume(volume)
+			if mount == "" {
+				continue
+			}
+			volumeName := fmt.Sprintf("%s-%d", name, i)
+			v := k8sVolume{Name: volumeName}
+			if source == "" || strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+				if source == "" {
+					continue // anonymous volumes have no Kubernetes equivalent worth exporting
+				}
+				if !filepath.IsAbs(source) {
+					source = filepath.Join(filepath.Dir(service.ComposeFile), source)
+				}
+				v.HostPath = map[string]string{"path": source}
+			} else {
+				claim := k8sName(source)
+				v.PersistentVolumeClaim = map[string]string{"claimName": claim}
+				if !claims[namespace+"/"+claim] {
+					claims[namespace+"/"+claim] = true
+					objects = append(objects, k8sObject{
+						APIVersion: "v1",
+						Kind:       "PersistentVolumeClaim",
+						Metadata:   k8sMetadata{Name: claim, Namespace: namespace, Labels: map[string]string{"app.kubernetes.io/managed-by": "dockermi"}},
+						Spec: k8sClaimSpec{
+							AccessModes: []string{"ReadWriteOnce"},
+							Resources:   map[string]map[string]string{"requests": {"storage": "1Gi"}},
+						},
+					})
+				}
+			}
+			pod.Volumes = append(pod.Volumes, v)
+			container.VolumeMounts = append(container.VolumeMounts, k8sVolumeMount{Name: volumeName, MountPath: mount, ReadOnly: readOnly})
+		}
+
+		for _, after := range service.After {
+			dependency := services[after]
+			ports := containerPorts(dependency.Definition.Ports)
+			if len(ports) == 0 {
+				continue // nothing to probe; the service has no Kubernetes Service
+			}
+			host := k8sName(dependency.Target)
+			if ns := namespaceOf(dependency, opts); ns != namespace {
+				if ns == "" {
+					// The dependency lands in whatever namespace the manifests are applied to
+					return fmt.Errorf("%s depends on %s, which has no dockermi.key and so no namespace to reach it in", service.ServiceName, dependency.ServiceName)
+				}
+				host += "." + ns
+			}
+			pod.InitContainers = append(pod.InitContainers, k8sContainer{
+				Name:    "wait-for-" + k8sName(dependency.Target),
+				Image:   opts.WaitImage,
+				Command: []string{"sh", "-c", fmt.Sprintf("until nc -z %s %d; do echo waiting for %s; sleep 2; done", host, ports[0].number, host)},
+			})
+		}
+		pod.Containers = []k8sContainer{container}
+
+		objects = append(objects, k8sObject{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Metadata: k8sMetadata{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      labels,
+				Annotations: map[string]string{"dockermi.order": service.Order, "dockermi.compose-file": service.ComposeFile},
+			},
+			Spec: k8sDeploymentSpec{
+				Replicas: 1,
+				Selector: k8sSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": name}},
+				Template: k8sPodTemplate{Metadata: k8sMetadata{Labels: labels}, Spec: pod},
+			},
+		})
+
+		if len(container.Ports) > 0 {
+			spec := k8sServiceSpec{Selector: map[string]string{"app.kubernetes.io/name": name}}
+			for _, port := range container.Ports {
+				spec.Ports = append(spec.Ports, k8sPort{
+					Name:       fmt.Sprintf("%s-%d", strings.ToLower(port.Protocol), port.ContainerPort),
+					Port:       port.ContainerPort,
+					TargetPort: port.ContainerPort,
+					Protocol:   port.Protocol,
+				})
+			}
+			objects = append(objects, k8sObject{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Metadata:   k8sMetadata{Name: name, Namespace: namespace, Labels: labels},
+				Spec:       spec,
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, data.Header); err != nil {
+		return err
+	}
+	for _, object := range objects {
+		out, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namespaceOf returns the namespace of service, empty when namespaces are not used.
+func namespaceOf(service script.Service, opts KubernetesOptions) string {
+	if !opts.NamespaceByKey || len(service.Keys) == 0 {
+		return ""
+	}
+	return k8sName(service.Keys[0])
+}
+
+// k8sName converts name into a valid Kubernetes object name (RFC 1123 label).
+func k8sName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	name = strings.Trim(name, "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	return name
+}
+
+// k8sEnvironment converts the compose environment into container env vars sorted by name.
+func k8sEnvironment(environment map[string]string) []k8sEnv {
+	names := make([]string, 0, len(environment))
+	for name := range environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]k8sEnv, 0, len(names))
+	for _, name := range names {
+		env = append(env, k8sEnv{Name: name, Value: environment[name]})
+	}
+	return env
+}
+
+// k8sReadinessProbe converts a compose healthcheck into an exec readiness probe.
+func k8sReadinessProbe(healthcheck *DockermiTypes.Healthcheck) *k8sProbe {
+	if healthcheck == nil || len(healthcheck.Test) == 0 {
+		return nil
+	}
+
+	var command []string
+	switch healthcheck.Test[0] {
+	case "NONE":
+		return nil
+	case "CMD":
+		command = healthcheck.Test[1:]
+	case "CMD-SHELL":
+		command = []string{"sh", "-c", strings.Join(healthcheck.Test[1:], " ")}
+	default:
+		command = healthcheck.Test
+	}
+
+	return &k8sProbe{
+		Exec:             map[string][]string{"command": command},
+		PeriodSeconds:    seconds(healthcheck.Interval),
+		TimeoutSeconds:   seconds(healthcheck.Timeout),
+		FailureThreshold: healthcheck.Retries,
+	}
+}
+
+// seconds converts a compose duration such as "10s" or "1m30s" into whole seconds.
+func seconds(duration string) int {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0
+	}
+	return int(d.Seconds())
+}
+
+// containerPort is a port a container listens on.
+type containerPort struct {
+	number   int
+	protocol string
+}
+
+// containerPorts extracts the container side of compose port mappings such as
+// "8080:80", "127.0.0.1:5432:5432" or "53:53/udp". A container port published
+// on several host ports is returned once.
+func containerPorts(ports []string) []containerPort {
+	var result []containerPort
+	seen := make(map[containerPort]bool)
+	for _, port := range ports {
+		protocol := "TCP"
+		if i := strings.LastIndexByte(port, '/'); i >= 0 {
+			protocol = strings.ToUpper(port[i+1:])
+			port = port[:i]
+		}
+		parts := strings.Split(port, ":")
+		target := parts[len(parts)-1]
+		if i := strings.IndexByte(target, '-'); i >= 0 {
+			target = target[:i] // a range maps the first port only
+		}
+		if number, err := strconv.Atoi(target); err == nil {
+			port := containerPort{number: number, protocol: protocol}
+			if !seen[port] {
+				seen[port] = true
+				result = append(result, port)
+			}
+		}
+	}
+	return result
+}
+
+// splitVolume splits a compose volume ("source:target[:mode]" or "target") into
+// its mount path, source and read-only flag.
+func splitVolume(volume string) (mount, source string, readOnly bool) {
+	parts := strings.Split(volume, ":")
+	switch len(parts) {
+	case 1:
+		return parts[0], "", false
+	case 2:
+		return parts[1], parts[0], false
+	default:
+		return parts[1], parts[0], strings.Contains(parts[2], "ro")
+	}
+}
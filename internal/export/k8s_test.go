@@ -0,0 +1,108 @@
+package export_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/export"
+	"github.com/mkhuda/dockermi/internal/script"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExportKubernetes(t *testing.T) {
+	projectDir := t.TempDir()
+	compose := `services:
+  db:
+    image: postgres:16
+    ports:
+      - "5432:5432"
+    environment:
+      POSTGRES_DB: app
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready"]
+      interval: 10s
+    labels:
+      dockermi.order: "1"
+      dockermi.active: "true"
+      dockermi.key: "backend"
+  api:
+    image: myapi:latest
+    ports:
+      - "127.0.0.1:8080:80"
+      - "8081:80"
+      - "8443:443"
+    environment:
+      - DB_HOST=db
+    labels:
+      dockermi.order: "2"
+      dockermi.active: "true"
+      dockermi.key: "frontend"
+`
+	if err := os.WriteFile(filepath.Join(projectDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	services, err := dockercompose.FindServices(projectDir, false)
+	if err != nil {
+		t.Fatalf("Error finding services: %v", err)
+	}
+
+	var buf bytes.Buffer
+	data := script.NewData(services, script.Header{Version: "test"})
+	if err := export.Kubernetes(&buf, data, export.KubernetesOptions{NamespaceByKey: true}); err != nil {
+		t.Fatalf("Error exporting manifests: %v", err)
+	}
+
+	var kinds []string
+	for _, document := range strings.Split(buf.String(), "---\n")[1:] {
+		var object struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+			t.Fatalf("Invalid manifest %v: %v", document, err)
+		}
+		kinds = append(kinds, object.Kind+"/"+object.Metadata.Namespace+"/"+object.Metadata.Name)
+	}
+	expectedKinds := "Namespace//backend Namespace//frontend PersistentVolumeClaim/backend/pgdata Deployment/backend/db Service/backend/db Deployment/frontend/api Service/frontend/api"
+	if strings.Join(kinds, " ") != expectedKinds {
+		t.Fatalf("Expected objects %v, got: %v", expectedKinds, kinds)
+	}
+
+	for _, expected := range []string{
+		"until nc -z db.backend 5432",
+		"containerPort: 80\n",
+		"- pg_isready\n",
+		"periodSeconds: 10\n",
+		"name: DB_HOST\n          value: db\n",
+		"claimName: pgdata\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("Expected manifests to contain %q, got:\n%v", expected, buf.String())
+		}
+	}
+
+	// api publishes port 80 twice: the Service gets it once, under a unique name
+	if strings.Count(buf.String(), "name: tcp-80\n") != 1 || !strings.Contains(buf.String(), "name: tcp-443\n") {
+		t.Fatalf("Expected one tcp-80 and one tcp-443 Service port, got:\n%v", buf.String())
+	}
+
+	// Without a key, db has no namespace api could reach it in
+	for i := range data.Services {
+		if data.Services[i].ServiceName == "db" {
+			data.Services[i].Keys = nil
+		}
+	}
+	if err := export.Kubernetes(&bytes.Buffer{}, data, export.KubernetesOptions{NamespaceByKey: true}); err == nil || !strings.Contains(err.Error(), "api depends on db") {
+		t.Fatalf("Expected an error for the dependency on db without a key, got: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mkhuda/dockermi/internal/script"
+)
+
+//go:embed templates/unit.tmpl
+var unitTemplate string
+
+//go:embed templates/target.tmpl
+var targetTemplate string
+
+// SystemdOptions controls the generated systemd units.
+type SystemdOptions struct {
+	// OutputDir is the directory the unit files are written to.
+	OutputDir string
+	// PerPhase generates one unit per dockermi.order phase instead of one per service.
+	PerPhase bool
+	// GroupTargets adds a dockermi-<key>.target unit for every dockermi.key group.
+	GroupTargets bool
+	// Rootless generates user units (systemctl --user) for rootless Podman,
+	// which need no system engine unit.
+	Rootless bool
+}
+
+// engineUnit returns the unit running the engine the compose tool of data
+// talks to: docker.service for Docker, podman.socket for Podman and none for
+// rootless Podman, which runs containers in the user session.
+func engineUnit(data script.Data, opts SystemdOptions) string {
+	switch {
+	case !data.Podman:
+		return "docker.service"
+	case opts.Rootless:
+		return ""
+	default:
+		return "podman.socket"
+	}
+}
+
+// unit is the data passed to the unit and target templates.
+type unit struct {
+	Name             string
+	Header           string
+	Description      string
+	Requires         []string
+	After            []string
+	WantedBy         string
+	WorkingDirectory string
+	Environment      []string
+	ExecStart        []string
+	ExecStop         []string
+}
+
+// Systemd writes systemd units starting the services of data in dockermi order
+// and returns the paths of the written files. Every unit requires and is ordered
+// after the engine unit and the units of the previous dockermi.order.
+func Systemd(data script.Data, opts SystemdOptions) ([]string, error) {
+	funcs := template.FuncMap{"join": strings.Join}
+	unitTmpl := template.Must(template.New("unit.tmpl").Funcs(funcs).Parse(unitTemplate))
+	targetTmpl := template.Must(template.New("target.tmpl").Funcs(funcs).Parse(targetTemplate))
+
+	var units []unit
+	serviceUnits := make(map[string]string) // service target -> unit name
+	if opts.PerPhase {
+		previous := ""
+		for _, phase := range data.Phases {
+			u := unit{
+				Name:        "dockermi-phase-" + phase.Order + ".service",
+				Header:      data.Header,
+				Description: fmt.Sprintf("dockermi: services with dockermi.order %s", phase.Order),
+			}
+			if previous != "" {
+				u.After = []string{previous}
+			}
+			for _, service := range phase.Services {
+				u.ExecStart = append(u.ExecStart, composeCommand(data.ComposeCommand, service, "up", "-d"))
+				serviceUnits[service.Target] = u.Name
+			}
+			for i := len(phase.Services) - 1; i >= 0; i-- {
+				u.ExecStop = append(u.ExecStop, composeCommand(data.ComposeCommand, phase.Services[i], "stop"))
+			}
+			units = append(units, u)
+			previous = u.Name
+		}
+	} else {
+		for _, service := range data.Services {
+			u := unit{
+				Name:             serviceUnitName(service.Target),
+				Header:           data.Header,
+				Description:      fmt.Sprintf("dockermi: %s (%s)", service.ServiceName, service.ComposeFile),
+				WorkingDirectory: escape(filepath.Dir(service.ComposeFile)),
+				ExecStart:        []string{composeCommand(data.ComposeCommand, service, "up", "-d")},
+				ExecStop:         []string{composeCommand(data.ComposeCommand, service, "stop")},
+			}
+			for _, after := range service.After {
+				u.After = append(u.After, serviceUnitName(after))
+			}
+			units = append(units, u)
+			serviceUnits[service.Target] = u.Name
+		}
+	}
+
+	engine := engineUnit(data, opts)
+	for i := range units {
+		if engine != "" {
+			units[i].Requires = append([]string{engine}, units[i].After...)
+		} else {
+			units[i].Requires = units[i].After
+		}
+	}
+
+	if data.Engine != nil {
+		// systemd does not pass a user environment, so the engine is always set
+		for i := range units {
+			units[i].Environment = []string{quote(data.Engine.Name + "=" + data.Engine.Value)}
+		}
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	wantedBy := "multi-user.target"
+	if opts.Rootless {
+		wantedBy = "default.target"
+	}
+
+	var written []string
+	for _, u := range units {
+		u.WantedBy = wantedBy
+		path, err := writeUnit(opts.OutputDir, u, unitTmpl)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	if opts.GroupTargets {
+		for _, group := range data.Groups {
+			u := unit{
+				Name:        "dockermi-" + group.Target + ".target",
+				Header:      data.Header,
+				Description: fmt.Sprintf("dockermi: %s group", group.Key),
+				WantedBy:    wantedBy,
+			}
+			seen := make(map[string]bool)
+			for _, service := range group.Services {
+				if name := serviceUnits[service.Target]; !seen[name] {
+					seen[name] = true
+					u.After = append(u.After, name)
+				}
+			}
+			path, err := writeUnit(opts.OutputDir, u, targetTmpl)
+			if err != nil {
+				return written, err
+			}
+			written = append(written, path)
+		}
+	}
+
+	return written, nil
+}
+
+// serviceUnitName returns the unit name of a service target.
+func serviceUnitName(target string) string {
+	return "dockermi-" + target + ".service"
+}
+
+// composeCommand returns the ExecStart/ExecStop line running a compose action for service.
+func composeCommand(compose string, service script.Service, action ...string) string {
+	args := append(strings.Fields(compose), "-f", quote(service.ComposeFile))
+	args = append(args, action...)
+	args = append(args, quote(service.ServiceName))
+	return "/usr/bin/env " + strings.Join(args, " ")
+}
+
+// writeUnit renders u with tmpl into dir.
+func writeUnit(dir string, u unit, tmpl *template.Template) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, u); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, u.Name)
+	return path, os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// quote double-quotes s for a systemd command line.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(escape(s)) + `"`
+}
+
+// escape doubles the '%' specifier and '$' variable characters systemd would expand.
+func escape(s string) string {
+	return strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+}
@@ -0,0 +1,85 @@
+package export_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/export"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestExportSystemd(t *testing.T) {
+	services := types.ServiceScriptReturn{
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml", Keys: []string{"api"}},
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web 100%/docker-compose.yml", Keys: []string{"api"}},
+	}
+	data := script.NewData(services, script.Header{Version: "test"})
+
+	outputDir := t.TempDir()
+	written, err := export.Systemd(data, export.SystemdOptions{OutputDir: outputDir, GroupTargets: true})
+	if err != nil {
+		t.Fatalf("Error exporting systemd units: %v", err)
+	}
+	if len(written) != 3 {
+		t.Fatalf("Expected 2 service units and 1 target, got: %v", written)
+	}
+
+	web, err := os.ReadFile(filepath.Join(outputDir, "dockermi-web.service"))
+	if err != nil {
+		t.Fatalf("Error reading unit: %v", err)
+	}
+	for _, expected := range []string{
+		"Requires=docker.service dockermi-db.service\n",
+		"After=docker.service dockermi-db.service\n",
+		"WorkingDirectory=/srv/web 100%%\n",
+		`ExecStart=/usr/bin/env docker-compose -f "/srv/web 100%%/docker-compose.yml" up -d "web"`,
+		`ExecStop=/usr/bin/env docker-compose -f "/srv/web 100%%/docker-compose.yml" stop "web"`,
+	} {
+		if !strings.Contains(string(web), expected) {
+			t.Fatalf("Expected unit to contain %q, got:\n%v", expected, string(web))
+		}
+	}
+
+	target, err := os.ReadFile(filepath.Join(outputDir, "dockermi-api.target"))
+	if err != nil {
+		t.Fatalf("Error reading target: %v", err)
+	}
+	if !strings.Contains(string(target), "Requires=dockermi-db.service dockermi-web.service\n") {
+		t.Fatalf("Expected target to require the group services, got:\n%v", string(target))
+	}
+}
+
+func TestExportSystemdEngineUnit(t *testing.T) {
+	services := types.ServiceScriptReturn{{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml"}}
+	podman, err := compose.Lookup("podman-compose")
+	if err != nil {
+		t.Fatalf("Error looking up podman-compose: %v", err)
+	}
+	data := script.NewData(services, script.Header{Version: "test"})
+	data.UseCompose(podman)
+
+	for _, tc := range []struct {
+		rootless bool
+		expected string
+		missing  string
+	}{
+		{false, "Requires=podman.socket\nAfter=podman.socket\n", "docker.service"},
+		{true, "WantedBy=default.target\n", "Requires="},
+	} {
+		outputDir := t.TempDir()
+		if _, err := export.Systemd(data, export.SystemdOptions{OutputDir: outputDir, Rootless: tc.rootless}); err != nil {
+			t.Fatalf("Error exporting systemd units: %v", err)
+		}
+		db, err := os.ReadFile(filepath.Join(outputDir, "dockermi-db.service"))
+		if err != nil {
+			t.Fatalf("Error reading unit: %v", err)
+		}
+		if !strings.Contains(string(db), tc.expected) || strings.Contains(string(db), tc.missing) {
+			t.Fatalf("Expected rootless=%v unit to contain %q and not %q, got:\n%v", tc.rootless, tc.expected, tc.missing, string(db))
+		}
+	}
+}
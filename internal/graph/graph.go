@@ -0,0 +1,269 @@
+// Package graph renders the start plan of a project as a dependency graph.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkhuda/dockermi/internal/plan"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// Cluster modes group the nodes of a graph by compose file or by dockermi.key.
+const (
+	ClusterFile = "file"
+	ClusterKey  = "key"
+)
+
+// Edge kinds.
+const (
+	// EdgeOrder links every service to the services of the next dockermi.order.
+	EdgeOrder = "order"
+	// EdgeDependsOn links a depends_on dependency to the service declaring it.
+	EdgeDependsOn = "depends_on"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{"dot", "mermaid", "json"}
+
+// Node is a service of the graph.
+type Node struct {
+	ID          string   `json:"id"`
+	Service     string   `json:"service"`
+	ComposeFile string   `json:"compose_file"`
+	Order       string   `json:"order,omitempty"`
+	Keys        []string `json:"keys,omitempty"`
+	Inactive    bool     `json:"inactive,omitempty"`
+	Cluster     string   `json:"cluster,omitempty"`
+}
+
+// Edge points from the service that starts first to the service waiting for it.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// Graph is the dependency graph of a project.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// New builds the graph of services found below root. Active services come
+// first in start order, followed by the inactive ones, which have no order
+// edges. clusterBy is ClusterFile, ClusterKey or empty for no clusters.
+func New(root string, services []DockermiTypes.ServiceScript, clusterBy string) (Graph, error) {
+	if clusterBy != "" && clusterBy != ClusterFile && clusterBy != ClusterKey {
+		return Graph{}, fmt.Errorf("unknown cluster mode: %s (supported: %s, %s)", clusterBy, ClusterFile, ClusterKey)
+	}
+
+	var active, inactive []DockermiTypes.ServiceScript
+	for _, service := range services {
+		if service.Inactive {
+			inactive = append(inactive, service)
+		} else {
+			active = append(active, service)
+		}
+	}
+	plan.Sort(active)
+	plan.Sort(inactive)
+
+	g := Graph{Nodes: []Node{}, Edges: []Edge{}}
+	ids := make(map[string]string) // plan.ID -> node ID
+	for _, service := range append(active, inactive...) {
+		node := Node{
+			ID:          fmt.Sprintf("n%d", len(g.Nodes)),
+			Service:     service.ServiceName,
+			ComposeFile: service.ComposeFile,
+			Order:       service.Order,
+			Keys:        service.Keys,
+			Inactive:    service.Inactive,
+		}
+		switch clusterBy {
+		case ClusterFile:
+			node.Cluster = relative(root, service.ComposeFile)
+		case ClusterKey:
+			if len(service.Keys) > 0 {
+				node.Cluster = service.Keys[0]
+			}
+		}
+		ids[plan.ID(service)] = node.ID
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	phases := plan.Phases(active)
+	for i := 1; i < len(phases); i++ {
+		for _, from := range phases[i-1].Services {
+			for _, to := range phases[i].Services {
+				g.Edges = append(g.Edges, Edge{From: ids[plan.ID(from)], To: ids[plan.ID(to)], Kind: EdgeOrder})
+			}
+		}
+	}
+
+	for _, service := range append(active, inactive...) {
+		for _, dependency := range service.Definition.DependsOn {
+			// depends_on only refers to services of the same compose file
+			from, ok := ids[service.ComposeFile+"#"+dependency]
+			if !ok {
+				continue
+			}
+			g.Edges = append(g.Edges, Edge{From: from, To: ids[plan.ID(service)], Kind: EdgeDependsOn})
+		}
+	}
+
+	return g, nil
+}
+
+// Write renders g to w in the given format.
+func Write(w io.Writer, g Graph, format string) error {
+	switch format {
+	case "dot":
+		return writeDot(w, g)
+	case "mermaid":
+		return writeMermaid(w, g)
+	case "json":
+		data, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	default:
+		return fmt.Errorf("unknown graph format: %s (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// clusters returns the cluster names in order of first appearance, with the
+// nodes of each cluster. Nodes without a cluster are listed under "".
+func (g Graph) clusters() ([]string, map[string][]Node) {
+	var names []string
+	nodes := make(map[string][]Node)
+	for _, node := range g.Nodes {
+		if _, ok := nodes[node.Cluster]; !ok && node.Cluster != "" {
+			names = append(names, node.Cluster)
+		}
+		nodes[node.Cluster] = append(nodes[node.Cluster], node)
+	}
+	return names, nodes
+}
+
+// writeDot renders g as a Graphviz digraph.
+func writeDot(w io.Writer, g Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph dockermi {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+
+	names, nodes := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(name))
+		for _, node := range nodes[name] {
+			fmt.Fprintf(&b, "    %s\n", dotNode(node))
+		}
+		b.WriteString("  }\n")
+	}
+	for _, node := range nodes[""] {
+		fmt.Fprintf(&b, "  %s\n", dotNode(node))
+	}
+
+	for _, edge := range g.Edges {
+		if edge.Kind == EdgeDependsOn {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=%s];\n", edge.From, edge.To, dotQuote(edge.Kind))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", edge.From, edge.To)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotNode returns the statement declaring node in a digraph.
+func dotNode(node Node) string {
+	if node.Inactive {
+		return fmt.Sprintf(`%s [label=%s, style="rounded,filled,dashed", color="#999999", fillcolor="#eeeeee", fontcolor="#999999"];`,
+			node.ID, dotQuote(node.Service+`\n`+describe(node)))
+	}
+	return fmt.Sprintf("%s [label=%s];", node.ID, dotQuote(node.Service+`\n`+describe(node)))
+}
+
+// dotQuote quotes s as a DOT string. Backslash sequences such as \n are kept.
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// writeMermaid renders g as a Mermaid flowchart.
+func writeMermaid(w io.Writer, g Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	names, nodes := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(&b, "  subgraph c%d[%s]\n", i, mermaidQuote(name))
+		for _, node := range nodes[name] {
+			fmt.Fprintf(&b, "    %s\n", mermaidNode(node))
+		}
+		b.WriteString("  end\n")
+	}
+	for _, node := range nodes[""] {
+		fmt.Fprintf(&b, "  %s\n", mermaidNode(node))
+	}
+
+	for _, edge := range g.Edges {
+		if edge.Kind == EdgeDependsOn {
+			fmt.Fprintf(&b, "  %s -. %s .-> %s\n", edge.From, edge.Kind, edge.To)
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", edge.From, edge.To)
+		}
+	}
+
+	var inactive []string
+	for _, node := range g.Nodes {
+		if node.Inactive {
+			inactive = append(inactive, node.ID)
+		}
+	}
+	if len(inactive) > 0 {
+		b.WriteString("  classDef inactive fill:#eeeeee,stroke:#999999,color:#999999,stroke-dasharray:4\n")
+		fmt.Fprintf(&b, "  class %s inactive\n", strings.Join(inactive, ","))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidNode returns the statement declaring node in a flowchart.
+func mermaidNode(node Node) string {
+	return fmt.Sprintf("%s[%s]", node.ID, mermaidQuote(node.Service+"<br/>"+describe(node)))
+}
+
+// mermaidQuote quotes s as a Mermaid label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// describe returns the second line of a node label.
+func describe(node Node) string {
+	if node.Inactive {
+		return "inactive"
+	}
+	if node.Order == "" {
+		return "no order"
+	}
+	return "order " + node.Order
+}
+
+// relative returns path relative to root, or path itself when it is not below root.
+func relative(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,110 @@
+package graph_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/graph"
+)
+
+func TestGraph(t *testing.T) {
+	projectDir := t.TempDir()
+	compose := `services:
+  db:
+    image: postgres:16
+    labels:
+      dockermi.order: "1"
+      dockermi.active: "true"
+      dockermi.key: "backend"
+  api:
+    image: myapi:latest
+    depends_on:
+      db:
+        condition: service_healthy
+    labels:
+      dockermi.order: "2"
+      dockermi.active: "true"
+      dockermi.key: "backend"
+  worker:
+    image: myworker:latest
+    depends_on:
+      - db
+    labels:
+      dockermi.order: "3"
+      dockermi.active: "false"
+`
+	if err := os.WriteFile(filepath.Join(projectDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	services, err := dockercompose.FindAllServices(projectDir, false)
+	if err != nil {
+		t.Fatalf("Error finding services: %v", err)
+	}
+
+	g, err := graph.New(projectDir, services, graph.ClusterFile)
+	if err != nil {
+		t.Fatalf("Error building graph: %v", err)
+	}
+
+	var nodes []string
+	for _, node := range g.Nodes {
+		nodes = append(nodes, fmt.Sprintf("%s:%s:%v:%s", node.ID, node.Service, node.Inactive, node.Cluster))
+	}
+	expectedNodes := "n0:db:false:docker-compose.yml n1:api:false:docker-compose.yml n2:worker:true:docker-compose.yml"
+	if strings.Join(nodes, " ") != expectedNodes {
+		t.Fatalf("Expected nodes %v, got: %v", expectedNodes, nodes)
+	}
+
+	var edges []string
+	for _, edge := range g.Edges {
+		edges = append(edges, edge.From+"->"+edge.To+":"+edge.Kind)
+	}
+	// The inactive worker only keeps its depends_on edge
+	expectedEdges := "n0->n1:order n0->n1:depends_on n0->n2:depends_on"
+	if strings.Join(edges, " ") != expectedEdges {
+		t.Fatalf("Expected edges %v, got: %v", expectedEdges, edges)
+	}
+
+	for format, expected := range map[string][]string{
+		"dot": {
+			"subgraph cluster_0 {\n    label=\"docker-compose.yml\";",
+			`n0 [label="db\norder 1"];`,
+			`n2 [label="worker\ninactive", style="rounded,filled,dashed"`,
+			"n0 -> n1;",
+			`n0 -> n2 [style=dashed, label="depends_on"];`,
+		},
+		"mermaid": {
+			"flowchart LR\n",
+			`subgraph c0["docker-compose.yml"]`,
+			`n1["api<br/>order 2"]`,
+			"n0 --> n1\n",
+			"n0 -. depends_on .-> n2\n",
+			"class n2 inactive\n",
+		},
+		"json": {
+			`"service": "worker"`,
+			`"inactive": true`,
+			`"kind": "depends_on"`,
+		},
+	} {
+		var buf bytes.Buffer
+		if err := graph.Write(&buf, g, format); err != nil {
+			t.Fatalf("Error writing %s graph: %v", format, err)
+		}
+		for _, e := range expected {
+			if !strings.Contains(buf.String(), e) {
+				t.Fatalf("Expected %s graph to contain %q, got:\n%v", format, e, buf.String())
+			}
+		}
+	}
+
+	if _, err := graph.New(projectDir, services, "color"); err == nil {
+		t.Fatalf("Expected an error for an unknown cluster mode")
+	}
+}
@@ -0,0 +1,21 @@
+// Package groupkey validates the group keys of 'dockermi.key' labels, which
+// become part of the file names of group scripts.
+package groupkey
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// pattern matches the group keys that can be used in file names.
+var pattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Validate checks that key only holds letters, digits, '.', '_' and '-', so
+// that it cannot point outside the dockermi home directory once it is part of
+// a file name.
+func Validate(key string) error {
+	if !pattern.MatchString(key) || key == "." || key == ".." {
+		return fmt.Errorf("invalid key %q: only letters, digits, '.', '_' and '-' are allowed", key)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package groupkey_test
+
+import (
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/groupkey"
+)
+
+func TestValidate(t *testing.T) {
+	for _, key := range []string{"billing", "api-v2", "team_a.web"} {
+		if err := groupkey.Validate(key); err != nil {
+			t.Fatalf("Expected %q to be valid, got: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "../../x", "a/b", `a\b`, "..", "web app", "key$"} {
+		if err := groupkey.Validate(key); err == nil {
+			t.Fatalf("Expected %q to be rejected", key)
+		}
+	}
+}
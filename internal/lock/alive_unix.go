@@ -0,0 +1,12 @@
+//go:build !windows
+
+package lock
+
+import "syscall"
+
+// alive reports whether a process with the given PID exists. Signal 0 only
+// checks for it; EPERM means it exists but belongs to another user.
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
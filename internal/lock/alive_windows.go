@@ -0,0 +1,16 @@
+//go:build windows
+
+package lock
+
+import "os"
+
+// alive reports whether a process with the given PID exists. On Windows
+// FindProcess opens the process, which fails once it is gone.
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,215 @@
+// Package lock provides advisory lock files keeping two dockermi runs from
+// starting and stopping the same services at the same time.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	dockermiUtils "github.com/mkhuda/dockermi/utils"
+)
+
+// Path returns the lock file of the project at root. Group scripts share the
+// lock of their project, as their services, and the services shared between
+// groups, are those of the project.
+func Path(root string) string {
+	return filepath.Join(root, ".dockermi", "run.lock")
+}
+
+// Info describes the holder of a lock, as stored in the lock file.
+type Info struct {
+	PID     int       `json:"pid"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// NewInfo returns the Info of the current process running command.
+func NewInfo(user, command string) Info {
+	host, _ := os.Hostname()
+	return Info{PID: os.Getpid(), User: user, Host: host, Command: command, Started: time.Now()}
+}
+
+// String describes the holder, e.g. "ana@devbox (pid 4121, up) since 09:12:44".
+func (i Info) String() string {
+	since := i.Started.Local().Format("15:04:05")
+	if time.Since(i.Started) > 24*time.Hour {
+		since = i.Started.Local().Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("%s@%s (pid %d, %s) since %s", i.User, i.Host, i.PID, i.Command, since)
+}
+
+// same reports whether i and other describe the same holder.
+func (i Info) same(other Info) bool {
+	return i.PID == other.PID && i.Host == other.Host && i.User == other.User && i.Started.Equal(other.Started)
+}
+
+// Stale reports whether the holder is known to be gone: it ran on this host
+// and its process no longer exists, so the system released its lock. Holders
+// on other hosts, e.g. through a shared file system, are never stale.
+func (i Info) Stale() bool {
+	host, err := os.Hostname()
+	if err != nil || i.Host != host || i.PID <= 0 {
+		return false
+	}
+	return !alive(i.PID)
+}
+
+// HeldError is returned when a lock is held by another run.
+type HeldError struct {
+	Path   string
+	Holder Info
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("locked by %s (%s)", e.Holder, e.Path)
+}
+
+// errLocked is returned by lockFile when another open file holds the lock.
+var errLocked = errors.New("locked")
+
+// Lock is an acquired lock file.
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// Acquire takes an advisory lock on the file at path and records info in it,
+// failing with a *HeldError when another run holds it. The system releases
+// the lock when its process exits, so a run that was killed never leaves a
+// lock behind. The file, and its directory, are created writable by the
+// group, so that everyone in it can take the lock.
+func Acquire(path string, info Info) (*Lock, error) {
+	if err := dockermiUtils.MkdirShared(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openShared(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if err == errLocked {
+			return nil, &HeldError{Path: path, Holder: holder(path)}
+		}
+		return nil, err
+	}
+
+	l := &Lock{file: file, path: path}
+	if err := l.write(append(data, '\n')); err != nil {
+		l.Release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// openShared opens the file at path for reading and writing, creating it
+// writable by the group regardless of the umask.
+func openShared(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0664)
+	if os.IsExist(err) {
+		return os.OpenFile(path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(0664); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// write replaces the content of the lock file with data.
+func (l *Lock) write(data []byte) error {
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Wait acquires the lock at path like Acquire, retrying every interval while
+// it is held until ctx is done. held is called with the holder whenever it
+// changes, e.g. to tell the user who they are waiting for.
+func Wait(ctx context.Context, path string, info Info, interval time.Duration, held func(Info)) (*Lock, error) {
+	var last Info
+	for {
+		l, err := Acquire(path, info)
+		var heldErr *HeldError
+		if !errors.As(err, &heldErr) {
+			return l, err
+		}
+		if held != nil && !heldErr.Holder.same(last) {
+			held(heldErr.Holder)
+			last = heldErr.Holder
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the lock: %w", err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Read returns the holder recorded in the lock file at path. The file is
+// emptied when the lock is released, but still names a holder that was
+// killed; such a holder is Stale and its lock is free.
+func Read(path string) (Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	if len(data) == 0 {
+		return Info{}, fmt.Errorf("lock %s is not held", path)
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Info{}, fmt.Errorf("invalid lock %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// Release empties the lock file and releases the lock.
+func (l *Lock) Release() error {
+	err := l.file.Truncate(0)
+	if uerr := unlockFile(l.file); err == nil {
+		err = uerr
+	}
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Path returns the path of the lock file.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// holder returns the holder of the lock at path. The holder may not have
+// written the file yet, so one that cannot be read is reported as unknown.
+func holder(path string) Info {
+	info, err := Read(path)
+	if err != nil {
+		info = Info{User: "unknown", Command: "unknown"}
+		if stat, serr := os.Stat(path); serr == nil {
+			info.Started = stat.ModTime()
+		}
+	}
+	return info
+}
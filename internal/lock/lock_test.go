@@ -0,0 +1,130 @@
+package lock_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/lock"
+)
+
+func TestRunLock(t *testing.T) {
+	path := lock.Path(t.TempDir())
+	if filepath.Base(path) != "run.lock" {
+		t.Fatalf("Expected a lock per project root, got: %s", path)
+	}
+
+	held, err := lock.Acquire(path, lock.NewInfo("ana", "up"))
+	if err != nil {
+		t.Fatalf("Error acquiring the lock: %v", err)
+	}
+	var heldErr *lock.HeldError
+	if _, err := lock.Acquire(path, lock.NewInfo("ben", "down")); !errors.As(err, &heldErr) ||
+		heldErr.Holder.User != "ana" || heldErr.Holder.PID != os.Getpid() || !strings.Contains(err.Error(), "ana@") {
+		t.Fatalf("Expected the lock to be held by ana, got: %v", err)
+	}
+
+	// --wait-lock waits until the holder releases the lock, or times out
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := lock.Wait(ctx, path, lock.NewInfo("ben", "down"), time.Millisecond, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a timeout, got: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		held.Release()
+	}()
+	var waitedFor []string
+	waited, err := lock.Wait(context.Background(), path, lock.NewInfo("ben", "down"), time.Millisecond, func(holder lock.Info) {
+		waitedFor = append(waitedFor, holder.User)
+	})
+	if err != nil || len(waitedFor) != 1 || waitedFor[0] != "ana" {
+		t.Fatalf("Expected to wait once for ana, got: %v (%v)", waitedFor, err)
+	}
+	if info, err := lock.Read(path); err != nil || info.User != "ben" {
+		t.Fatalf("Expected ben to hold the lock, got: %+v (%v)", info, err)
+	}
+	if err := waited.Release(); err != nil {
+		t.Fatalf("Error releasing the lock: %v", err)
+	}
+	if _, err := lock.Read(path); err == nil {
+		t.Fatalf("Expected a released lock to name no holder")
+	}
+
+	// A lock file naming a dead process of this host is free
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Error running a short-lived process: %v", err)
+	}
+	stale := lock.NewInfo("ana", "up")
+	stale.PID = cmd.Process.Pid
+	if !stale.Stale() {
+		t.Fatalf("Expected a lock of a dead process to be stale: %+v", stale)
+	}
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Error writing stale lock: %v", err)
+	}
+	if l, err := lock.Acquire(path, lock.NewInfo("ben", "up")); err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got: %v", err)
+	} else {
+		l.Release()
+	}
+
+	// Locks of other hosts cannot be checked and are never stale
+	stale.Host = "another-host"
+	if stale.Stale() {
+		t.Fatalf("Expected a lock of another host not to be stale")
+	}
+}
+
+func TestRunLockConcurrent(t *testing.T) {
+	path := lock.Path(t.TempDir())
+
+	// Of the runs trying to take the lock at once, exactly one gets it
+	const runs = 20
+	var wg sync.WaitGroup
+	acquired := make(chan *lock.Lock, runs)
+	start := make(chan struct{})
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			l, err := lock.Acquire(path, lock.NewInfo(fmt.Sprintf("user%d", i), "up"))
+			var heldErr *lock.HeldError
+			if err == nil {
+				acquired <- l
+			} else if !errors.As(err, &heldErr) {
+				t.Errorf("Error acquiring the lock: %v", err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(acquired)
+
+	var holders []*lock.Lock
+	for l := range acquired {
+		holders = append(holders, l)
+	}
+	if len(holders) != 1 {
+		t.Fatalf("Expected exactly one run to hold the lock, got %d", len(holders))
+	}
+	if err := holders[0].Release(); err != nil {
+		t.Fatalf("Error releasing the lock: %v", err)
+	}
+	l, err := lock.Acquire(path, lock.NewInfo("ana", "up"))
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got: %v", err)
+	}
+	l.Release()
+}
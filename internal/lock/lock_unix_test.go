@@ -0,0 +1,63 @@
+//go:build !windows
+
+package lock_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/lock"
+)
+
+func TestLockDirPermissions(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(022))
+
+	path := lock.Path(t.TempDir())
+	l, err := lock.Acquire(path, lock.NewInfo("ana", "up"))
+	if err != nil {
+		t.Fatalf("Error acquiring the lock: %v", err)
+	}
+	defer l.Release()
+
+	// Everyone in the group must be able to release the lock and take it over
+	dir, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Error reading the lock directory: %v", err)
+	}
+	if perm := dir.Mode().Perm(); perm != 0775 {
+		t.Fatalf("Expected the lock directory to be created 0775, got %o", perm)
+	}
+	file, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Error reading the lock file: %v", err)
+	}
+	if perm := file.Mode().Perm(); perm != 0664 {
+		t.Fatalf("Expected the lock file to be created 0664, got %o", perm)
+	}
+}
+
+func TestLockDirUpgrade(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(022))
+
+	// A .dockermi directory created with the umask, e.g. by an older version,
+	// is made writable by the group
+	path := lock.Path(t.TempDir())
+	if err := os.Mkdir(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Error creating the lock directory: %v", err)
+	}
+	l, err := lock.Acquire(path, lock.NewInfo("ana", "up"))
+	if err != nil {
+		t.Fatalf("Error acquiring the lock: %v", err)
+	}
+	defer l.Release()
+
+	dir, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Error reading the lock directory: %v", err)
+	}
+	if perm := dir.Mode().Perm(); perm != 0775 {
+		t.Fatalf("Expected the lock directory to be made 0775, got %o", perm)
+	}
+}
@@ -0,0 +1,149 @@
+package plan
+
+import (
+	"sort"
+
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// ID returns the identifier used to match the same service across plans.
+func ID(service DockermiTypes.ServiceScript) string {
+	return service.ComposeFile + "#" + service.ServiceName
+}
+
+// Sort orders services for starting: ascending 'dockermi.order', then compose
+// file and service name so that equal orders always produce the same plan.
+func Sort(services []DockermiTypes.ServiceScript) {
+	sort.SliceStable(services, func(i, j int) bool {
+		if services[i].Order != services[j].Order {
+			return services[i].Order < services[j].Order
+		}
+		if services[i].ComposeFile != services[j].ComposeFile {
+			return services[i].ComposeFile < services[j].ComposeFile
+		}
+		return services[i].ServiceName < services[j].ServiceName
+	})
+}
+
+// Reverse returns a copy of services in reverse order, used for stopping.
+func Reverse(services []DockermiTypes.ServiceScript) []DockermiTypes.ServiceScript {
+	reversed := make([]DockermiTypes.ServiceScript, len(services))
+	for i, service := range services {
+		reversed[len(services)-1-i] = service
+	}
+	return reversed
+}
+
+// Diff describes how a start plan changed between two generations.
+type Diff struct {
+	Added     []DockermiTypes.ServiceScript
+	Removed   []DockermiTypes.ServiceScript
+	Reordered []DockermiTypes.ServiceScript
+}
+
+// Empty reports whether both plans are identical.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reordered) == 0
+}
+
+// Compare returns the difference between the old and new start plans. Both
+// slices are expected in start order. A service is reported as reordered when
+// its 'dockermi.order' changed, when both plans know it, or when it no longer
+// starts in the same order relative to the other services present in both
+// plans; when a service moves, only that service is reported, not the ones it
+// moved past.
+func Compare(old, new []DockermiTypes.ServiceScript) Diff {
+	var d Diff
+
+	oldIndex := make(map[string]int, len(old))
+	for _, service := range old {
+		oldIndex[ID(service)] = -1
+	}
+	newIDs := make(map[string]bool, len(new))
+	for _, service := range new {
+		newIDs[ID(service)] = true
+	}
+
+	var oldCommon []DockermiTypes.ServiceScript
+	for _, service := range old {
+		if newIDs[ID(service)] {
+			oldIndex[ID(service)] = len(oldCommon)
+			oldCommon = append(oldCommon, service)
+		} else {
+			d.Removed = append(d.Removed, service)
+		}
+	}
+
+	var newCommon []DockermiTypes.ServiceScript
+	var positions []int
+	for _, service := range new {
+		if i, ok := oldIndex[ID(service)]; ok {
+			newCommon = append(newCommon, service)
+			positions = append(positions, i)
+		} else {
+			d.Added = append(d.Added, service)
+		}
+	}
+
+	kept := inOrder(positions)
+	for i, service := range newCommon {
+		if !kept[i] || orderChanged(oldCommon[positions[i]], service) {
+			d.Reordered = append(d.Reordered, service)
+		}
+	}
+
+	return d
+}
+
+// orderChanged reports whether the dockermi.order of a service changed. Plans
+// read from scripts generated by older versions do not know the order, so an
+// empty order is never a change.
+func orderChanged(old, new DockermiTypes.ServiceScript) bool {
+	return old.Order != "" && new.Order != "" && old.Order != new.Order
+}
+
+// inOrder marks the longest increasing subsequence of positions: the largest
+// set of services that kept their relative order. Among equally long
+// subsequences the one ending first is kept.
+func inOrder(positions []int) []bool {
+	length := make([]int, len(positions))
+	prev := make([]int, len(positions))
+	end := -1
+	for i := range positions {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if positions[j] < positions[i] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if end < 0 || length[i] > length[end] {
+			end = i
+		}
+	}
+
+	kept := make([]bool, len(positions))
+	for i := end; i >= 0; i = prev[i] {
+		kept[i] = true
+	}
+	return kept
+}
+
+// Phase is a set of services sharing the same 'dockermi.order' value.
+type Phase struct {
+	Order    string
+	Services []DockermiTypes.ServiceScript
+}
+
+// Phases groups services, which must already be sorted with Sort, into
+// phases of equal 'dockermi.order' in start order.
+func Phases(services []DockermiTypes.ServiceScript) []Phase {
+	var phases []Phase
+	for _, service := range services {
+		if len(phases) == 0 || phases[len(phases)-1].Order != service.Order {
+			phases = append(phases, Phase{Order: service.Order})
+		}
+		last := &phases[len(phases)-1]
+		last.Services = append(last.Services, service)
+	}
+	return phases
+}
@@ -0,0 +1,70 @@
+package plan_test
+
+import (
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestPlanCompare(t *testing.T) {
+	db := types.ServiceScript{Order: "1", ServiceName: "db", ComposeFile: "/a/docker-compose.yml"}
+	cache := types.ServiceScript{Order: "1", ServiceName: "cache", ComposeFile: "/a/docker-compose.yml"}
+	web := types.ServiceScript{Order: "2", ServiceName: "web", ComposeFile: "/b/docker-compose.yml"}
+	worker := types.ServiceScript{Order: "3", ServiceName: "worker", ComposeFile: "/b/docker-compose.yml"}
+
+	if diff := plan.Compare([]types.ServiceScript{db, web}, []types.ServiceScript{db, web}); !diff.Empty() {
+		t.Fatalf("Expected identical plans to have no diff, got: %+v", diff)
+	}
+
+	current := []types.ServiceScript{worker, web, db, cache}
+	plan.Sort(current)
+	if plan.ID(current[0]) != plan.ID(cache) || plan.ID(current[3]) != plan.ID(worker) {
+		t.Fatalf("Expected plan sorted by order and name, got: %+v", current)
+	}
+
+	diff := plan.Compare([]types.ServiceScript{web, db, worker}, []types.ServiceScript{db, web, cache})
+	if len(diff.Added) != 1 || plan.ID(diff.Added[0]) != plan.ID(cache) {
+		t.Fatalf("Expected cache to be added, got: %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || plan.ID(diff.Removed[0]) != plan.ID(worker) {
+		t.Fatalf("Expected worker to be removed, got: %+v", diff.Removed)
+	}
+	if len(diff.Reordered) != 1 || plan.ID(diff.Reordered[0]) != plan.ID(web) {
+		t.Fatalf("Expected web to be reordered after db, got: %+v", diff.Reordered)
+	}
+}
+
+func TestPlanCompareMovedService(t *testing.T) {
+	var old []types.ServiceScript
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		old = append(old, types.ServiceScript{Order: "1", ServiceName: name, ComposeFile: "/srv/docker-compose.yml"})
+	}
+
+	// b moves after e: the services it moved past keep their relative order
+	new := []types.ServiceScript{old[0], old[2], old[3], old[4], old[1], old[5]}
+	diff := plan.Compare(old, new)
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Fatalf("Expected no added or removed services, got: %+v", diff)
+	}
+	if len(diff.Reordered) != 1 || diff.Reordered[0].ServiceName != "b" {
+		t.Fatalf("Expected only b to be reordered, got: %+v", diff.Reordered)
+	}
+
+	// A changed dockermi.order is reported even when the position is the same
+	f := old[5]
+	f.Order = "2"
+	diff = plan.Compare(old, append(append([]types.ServiceScript(nil), old[:5]...), f))
+	if len(diff.Reordered) != 1 || diff.Reordered[0].ServiceName != "f" {
+		t.Fatalf("Expected f to be reordered, got: %+v", diff.Reordered)
+	}
+
+	// Plans read from older scripts do not know the order
+	unknown := append([]types.ServiceScript(nil), old...)
+	for i := range unknown {
+		unknown[i].Order = ""
+	}
+	if diff := plan.Compare(unknown, old); !diff.Empty() {
+		t.Fatalf("Expected an unknown order not to be a change, got: %+v", diff)
+	}
+}
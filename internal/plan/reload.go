@@ -0,0 +1,143 @@
+package plan
+
+import (
+	"reflect"
+
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// Reload describes how to bring running services in line with changed
+// compose files. Every list is in start order, except Stop which is in stop order.
+type Reload struct {
+	// Recreate holds the services whose definition changed, followed by the
+	// services depending on them through depends_on.
+	Recreate []DockermiTypes.ServiceScript
+	// Start holds the services that are new to the plan.
+	Start []DockermiTypes.ServiceScript
+	// Stop holds the services that left the plan.
+	Stop []DockermiTypes.ServiceScript
+}
+
+// Empty reports whether nothing needs to be done.
+func (r Reload) Empty() bool {
+	return len(r.Recreate) == 0 && len(r.Start) == 0 && len(r.Stop) == 0
+}
+
+// PlanReload compares the services of two discoveries of the same script, a
+// project root or a dockermi.key group. A service is recreated when its image,
+// environment, ports, labels, volumes, healthcheck or depends_on changed,
+// together with the services depending on it: the services in later
+// dockermi.order phases, which start after it, and the services of the same
+// compose file that depend on it, directly or not.
+func PlanReload(old, new []DockermiTypes.ServiceScript) Reload {
+	var r Reload
+
+	previous := make(map[string]DockermiTypes.ServiceScript, len(old))
+	for _, service := range old {
+		previous[ID(service)] = service
+	}
+	current := make(map[string]bool, len(new))
+	for _, service := range new {
+		current[ID(service)] = true
+	}
+
+	recreate := make(map[string]bool)
+	for _, service := range new {
+		before, ok := previous[ID(service)]
+		if !ok {
+			continue
+		}
+		if definitionChanged(before.Definition, service.Definition) {
+			recreate[ID(service)] = true
+		}
+	}
+
+	// Add dependents until no more services are marked
+	for added := true; added; {
+		added = false
+		earliest, found := "", false
+		for _, service := range new {
+			if recreate[ID(service)] && (!found || service.Order < earliest) {
+				earliest, found = service.Order, true
+			}
+		}
+
+		for _, service := range new {
+			if recreate[ID(service)] {
+				continue
+			}
+			if _, ok := previous[ID(service)]; !ok {
+				continue
+			}
+			if found && service.Order > earliest {
+				recreate[ID(service)] = true
+				added = true
+				continue
+			}
+			for _, dependency := range service.Definition.DependsOn {
+				if recreate[service.ComposeFile+"#"+dependency] {
+					recreate[ID(service)] = true
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	sorted := append([]DockermiTypes.ServiceScript(nil), new...)
+	Sort(sorted)
+	for _, service := range sorted {
+		if recreate[ID(service)] {
+			r.Recreate = append(r.Recreate, service)
+		} else if _, ok := previous[ID(service)]; !ok {
+			r.Start = append(r.Start, service)
+		}
+	}
+
+	sortedOld := append([]DockermiTypes.ServiceScript(nil), old...)
+	Sort(sortedOld)
+	for _, service := range Reverse(sortedOld) {
+		if !current[ID(service)] {
+			r.Stop = append(r.Stop, service)
+		}
+	}
+	return r
+}
+
+// definitionChanged reports whether a running container of the service would
+// need to be recreated to pick up the new definition.
+func definitionChanged(old, new DockermiTypes.Service) bool {
+	return old.Image != new.Image ||
+		!equalStrings(old.Ports, new.Ports) ||
+		!equalStrings(old.Volumes, new.Volumes) ||
+		!equalStrings(old.DependsOn, new.DependsOn) ||
+		!equalMaps(old.Environment, new.Environment) ||
+		!equalMaps(old.Labels, new.Labels) ||
+		!reflect.DeepEqual(old.Healthcheck, new.Healthcheck)
+}
+
+// equalStrings compares two slices, treating nil and empty alike.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalMaps compares two maps, treating nil and empty alike.
+func equalMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package plan_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestPlanReload(t *testing.T) {
+	service := func(order, name, file string, definition types.Service) types.ServiceScript {
+		definition.Name = name
+		return types.ServiceScript{Order: order, ServiceName: name, ComposeFile: file, Definition: definition}
+	}
+	old := []types.ServiceScript{
+		service("1", "db", "/app/docker-compose.yml", types.Service{Image: "postgres:15"}),
+		service("2", "api", "/app/docker-compose.yml", types.Service{Image: "api", DependsOn: []string{"db"}}),
+		service("3", "worker", "/app/docker-compose.yml", types.Service{Image: "worker", DependsOn: []string{"api"}}),
+		service("3", "web", "/app/docker-compose.yml", types.Service{Image: "web"}),
+		service("1", "cache", "/cache/docker-compose.yml", types.Service{Image: "redis"}),
+	}
+	new := []types.ServiceScript{
+		service("1", "db", "/app/docker-compose.yml", types.Service{Image: "postgres:16"}),
+		service("2", "api", "/app/docker-compose.yml", types.Service{Image: "api", DependsOn: []string{"db"}}),
+		service("3", "worker", "/app/docker-compose.yml", types.Service{Image: "worker", DependsOn: []string{"api"}}),
+		service("3", "web", "/app/docker-compose.yml", types.Service{Image: "web", Environment: map[string]string{}}),
+		service("2", "search", "/search/docker-compose.yml", types.Service{Image: "opensearch"}),
+	}
+
+	names := func(services []types.ServiceScript) string {
+		var result []string
+		for _, s := range services {
+			result = append(result, s.ServiceName)
+		}
+		return strings.Join(result, ",")
+	}
+
+	reload := plan.PlanReload(old, new)
+	// The later phases start after db, so api, web and worker are recreated in start order
+	if names(reload.Recreate) != "db,api,web,worker" || names(reload.Start) != "search" || names(reload.Stop) != "cache" {
+		t.Fatalf("Expected recreate db,api,web,worker, start search and stop cache, got: %s / %s / %s",
+			names(reload.Recreate), names(reload.Start), names(reload.Stop))
+	}
+	if !plan.PlanReload(new, new).Empty() {
+		t.Fatalf("Expected no reload for unchanged services")
+	}
+
+	// Only queue changes: db in an earlier phase and mail in the same phase
+	// keep running, api and its dependent worker in the next phase do not
+	old = []types.ServiceScript{
+		service("1", "db", "/app/docker-compose.yml", types.Service{Image: "postgres:16"}),
+		service("2", "queue", "/queue/docker-compose.yml", types.Service{Image: "rabbitmq:3"}),
+		service("2", "mail", "/mail/docker-compose.yml", types.Service{Image: "mailpit"}),
+		service("3", "api", "/app/docker-compose.yml", types.Service{Image: "api"}),
+		service("3", "worker", "/app/docker-compose.yml", types.Service{Image: "worker", DependsOn: []string{"api"}}),
+	}
+	new = append([]types.ServiceScript(nil), old...)
+	new[1] = service("2", "queue", "/queue/docker-compose.yml", types.Service{Image: "rabbitmq:4"})
+	// An empty environment equals no environment
+	new[2] = service("2", "mail", "/mail/docker-compose.yml", types.Service{Image: "mailpit", Environment: map[string]string{}})
+	if reload := plan.PlanReload(old, new); names(reload.Recreate) != "queue,api,worker" {
+		t.Fatalf("Expected recreate queue,api,worker, got: %s", names(reload.Recreate))
+	}
+}
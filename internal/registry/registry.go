@@ -0,0 +1,162 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/groupkey"
+)
+
+// indexFileName is the name of the registry index stored inside the dockermi home directory.
+const indexFileName = "index.json"
+
+// Entry describes a single group script generated by 'dockermi create <key>'.
+type Entry struct {
+	Key          string    `json:"key"`
+	Root         string    `json:"root"`
+	ScriptPath   string    `json:"script_path"`
+	CreatedAt    time.Time `json:"created_at"`
+	ServiceCount int       `json:"service_count"`
+	Version      string    `json:"generator_version"`
+	// Context and Host are the default docker context or engine host of the
+	// group, used by 'dockermi up --group' and 'status --group'.
+	Context string `json:"context,omitempty"`
+	Host    string `json:"host,omitempty"`
+	// Shared lists the services (compose file#service name) the group shares
+	// with other groups. The group starts them only when they are not running
+	// and leaves them running when it stops.
+	Shared []string `json:"shared,omitempty"`
+}
+
+// Registry is the index of group scripts stored under ~/.dockermi.
+type Registry struct {
+	path   string
+	Groups map[string]Entry `json:"groups"`
+}
+
+// Dir returns the dockermi home directory (~/.dockermi), creating it when missing.
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	dockermiDir := filepath.Join(homeDir, ".dockermi")
+	if err := os.MkdirAll(dockermiDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	return dockermiDir, nil
+}
+
+// ScriptPath returns the location of the group script for the given key and
+// script extension (e.g. ".sh"). The key must pass groupkey.Validate.
+func ScriptPath(key, extension string) (string, error) {
+	if err := groupkey.Validate(key); err != nil {
+		return "", err
+	}
+	dockermiDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dockermiDir, fmt.Sprintf("dockermi-%s%s", key, extension)), nil
+}
+
+// Load reads the registry index from the dockermi home directory. A missing
+// index is not an error; an empty registry is returned instead.
+func Load() (*Registry, error) {
+	dockermiDir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		path:   filepath.Join(dockermiDir, indexFileName),
+		Groups: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("invalid registry index %s: %w", r.path, err)
+	}
+	if r.Groups == nil {
+		r.Groups = make(map[string]Entry)
+	}
+	return r, nil
+}
+
+// Save writes the registry index back to disk through a temporary file, so
+// that concurrent runs never leave a partially written index behind.
+func (r *Registry) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(r.path), "."+indexFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // no-op once the file has been renamed
+
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), r.path)
+}
+
+// Put adds or replaces the entry for e.Key.
+func (r *Registry) Put(e Entry) {
+	r.Groups[e.Key] = e
+}
+
+// Get returns the entry registered for key.
+func (r *Registry) Get(key string) (Entry, bool) {
+	e, ok := r.Groups[key]
+	return e, ok
+}
+
+// Remove deletes the entry for key together with its generated script and its backup.
+func (r *Registry) Remove(key string) error {
+	e, ok := r.Groups[key]
+	if !ok {
+		return fmt.Errorf("group not found: %s", key)
+	}
+
+	for _, path := range []string{e.ScriptPath, e.ScriptPath + ".bak"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	delete(r.Groups, key)
+	return nil
+}
+
+// List returns all entries sorted by key.
+func (r *Registry) List() []Entry {
+	entries := make([]Entry, 0, len(r.Groups))
+	for _, e := range r.Groups {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
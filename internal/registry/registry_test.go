@@ -0,0 +1,88 @@
+package registry_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/registry"
+)
+
+func TestGroupRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	reg, err := registry.Load()
+	if err != nil {
+		t.Fatalf("Error loading registry: %v", err)
+	}
+
+	scriptPath, err := registry.ScriptPath("key1", ".sh")
+	if err != nil {
+		t.Fatalf("Error resolving script path: %v", err)
+	}
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\n"), 0755); err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+
+	reg.Put(registry.Entry{Key: "key1", Root: "/tmp/project", ScriptPath: scriptPath, ServiceCount: 2, Version: "test"})
+	if err := reg.Save(); err != nil {
+		t.Fatalf("Error saving registry: %v", err)
+	}
+
+	reg, err = registry.Load()
+	if err != nil {
+		t.Fatalf("Error reloading registry: %v", err)
+	}
+	entry, ok := reg.Get("key1")
+	if !ok || entry.ServiceCount != 2 || entry.Root != "/tmp/project" {
+		t.Fatalf("Expected key1 entry to be persisted, got: %+v", entry)
+	}
+
+	if err := reg.Remove("key1"); err != nil {
+		t.Fatalf("Error removing group: %v", err)
+	}
+	if _, err := os.Stat(scriptPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected %v to be removed", scriptPath)
+	}
+	if len(reg.List()) != 0 {
+		t.Fatalf("Expected registry to be empty, got: %v", reg.List())
+	}
+}
+
+func TestScriptPathInvalidKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, key := range []string{"", "../../x", "a/b", `a\b`, "..", "web app", "key$"} {
+		if path, err := registry.ScriptPath(key, ".sh"); err == nil {
+			t.Fatalf("Expected no script path for %q, got: %s", key, path)
+		}
+	}
+}
+
+func TestRegistrySaveIsAtomic(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	reg, err := registry.Load()
+	if err != nil {
+		t.Fatalf("Error loading registry: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		reg.Put(registry.Entry{Key: fmt.Sprintf("key%d", i), Root: "/tmp/project"})
+		if err := reg.Save(); err != nil {
+			t.Fatalf("Error saving registry: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(home, ".dockermi"))
+	if err != nil {
+		t.Fatalf("Error reading the dockermi home directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "index.json" {
+		t.Fatalf("Expected only index.json to be left, got: %v", entries)
+	}
+	if reg, err = registry.Load(); err != nil || len(reg.List()) != 3 {
+		t.Fatalf("Expected 3 groups, got: %v (%v)", reg, err)
+	}
+}
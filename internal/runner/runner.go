@@ -0,0 +1,210 @@
+// Package runner runs compose commands for individual services.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/events"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/script"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// Runner runs compose commands for services, one service at a time.
+type Runner struct {
+	// Command is the compose command line, e.g. ["podman", "compose"].
+	// docker-compose is used when it is empty.
+	Command []string
+	// Env holds environment entries (NAME=value) added to every command, e.g.
+	// the variables selecting the engine.
+	Env []string
+	// Args are appended to every up command, e.g. the passthrough args of 'dockermi up'.
+	Args []string
+	// DryRun prints the commands instead of running them.
+	DryRun bool
+	// Stdout and Stderr receive the output of the commands; os.Stdout and
+	// os.Stderr when nil.
+	Stdout, Stderr io.Writer
+	// Observer receives the events of Run. It may be nil.
+	Observer events.Observer
+	// Wait blocks until a started service is healthy and returns its health
+	// (see events.Event). Services count as "started" once their compose
+	// command succeeded when it is nil.
+	Wait func(ctx context.Context, service DockermiTypes.ServiceScript) (string, error)
+	// Running reports whether a service is already running. Like the group
+	// scripts, Run does not start a Shared service that is already running,
+	// and never stops one. Shared services are always started when it is nil.
+	Running func(ctx context.Context, service DockermiTypes.ServiceScript) (bool, error)
+}
+
+// Run performs action ("up" or "down") for services, which must be in start
+// order: phase by phase, starting every service and waiting for it to become
+// healthy, or stopping them in reverse order. Shared services are handled as
+// described for r.Running. Events are sent to r.Observer. It stops at the
+// first service that fails.
+func (r Runner) Run(ctx context.Context, action string, services []DockermiTypes.ServiceScript) error {
+	phases := plan.Phases(services)
+	verb := []string{"up", "-d"}
+	if action == "down" {
+		phases = plan.Phases(plan.Reverse(services))
+		verb = []string{"stop"}
+	}
+
+	resolved := events.Event{Type: events.PlanResolved, Action: action}
+	for _, phase := range phases {
+		p := events.Phase{Order: phase.Order}
+		for _, service := range phase.Services {
+			p.Services = append(p.Services, events.Service{Service: service.ServiceName, ComposeFile: service.ComposeFile})
+		}
+		resolved.Phases = append(resolved.Phases, p)
+	}
+	r.emit(resolved)
+
+	start := time.Now()
+	err := r.runPhases(ctx, action, verb, phases)
+	finished := events.Event{Type: events.RunFinished, Action: action, Duration: time.Since(start)}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	r.emit(finished)
+	return err
+}
+
+// runPhases runs the compose command verb for every service of phases.
+func (r Runner) runPhases(ctx context.Context, action string, verb []string, phases []plan.Phase) error {
+	for _, phase := range phases {
+		phaseStart := time.Now()
+		for _, service := range phase.Services {
+			event := events.Event{Action: action, Service: service.ServiceName, ComposeFile: service.ComposeFile, Phase: phase.Order}
+			event.Type = events.ServiceStarting
+			r.emit(event)
+
+			serviceStart := time.Now()
+			health, command, err := r.runService(ctx, action, verb, service)
+			event.Duration, event.Command = time.Since(serviceStart), command
+			if err != nil {
+				event.Type, event.Error = events.ServiceFailed, err.Error()
+				r.emit(event)
+				return fmt.Errorf("%s: %w", service.ServiceName, err)
+			}
+			event.Type, event.Health = events.ServiceHealthy, health
+			r.emit(event)
+		}
+		r.emit(events.Event{Type: events.PhaseComplete, Action: action, Phase: phase.Order, Duration: time.Since(phaseStart)})
+	}
+	return nil
+}
+
+// runService runs the compose command of one service and, on up, waits for it
+// to become healthy. It returns the health the service reached and the time
+// the compose command took.
+func (r Runner) runService(ctx context.Context, action string, verb []string, service DockermiTypes.ServiceScript) (string, time.Duration, error) {
+	if service.Shared {
+		if action == "down" {
+			return "left running (shared)", 0, nil
+		}
+		if r.Running != nil {
+			running, err := r.Running(ctx, service)
+			if err != nil {
+				return "", 0, err
+			}
+			if running {
+				return "already running (shared)", 0, nil
+			}
+		}
+	}
+
+	start := time.Now()
+	err := r.run(service, append(append(append([]string(nil), verb...), service.ServiceName), r.Args...)...)
+	command := time.Since(start)
+	switch {
+	case err != nil:
+		return "", command, err
+	case action == "down":
+		return "stopped", command, nil
+	case r.Wait == nil || r.DryRun:
+		return "started", command, nil
+	default:
+		health, err := r.Wait(ctx, service)
+		return health, command, err
+	}
+}
+
+// emit sends event to the observer, if any.
+func (r Runner) emit(event events.Event) {
+	if r.Observer == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	r.Observer.Event(event)
+}
+
+// Apply performs a reload: the services that left the plan are stopped, then
+// the changed services and their dependents are recreated and the new services
+// are started, all in start order. It stops at the first failing command.
+func (r Runner) Apply(reload plan.Reload) error {
+	for _, service := range reload.Stop {
+		if err := r.run(service, "stop", service.ServiceName); err != nil {
+			return err
+		}
+	}
+
+	recreate := make(map[string]bool, len(reload.Recreate))
+	for _, service := range reload.Recreate {
+		recreate[plan.ID(service)] = true
+	}
+	start := append(append([]DockermiTypes.ServiceScript(nil), reload.Recreate...), reload.Start...)
+	plan.Sort(start)
+
+	for _, service := range start {
+		args := []string{"up", "-d", "--no-deps"}
+		if recreate[plan.ID(service)] {
+			// Dependents have an unchanged definition, so compose would keep them
+			args = append(args, "--force-recreate")
+		}
+		args = append(append(args, service.ServiceName), r.Args...)
+		if err := r.run(service, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs the compose command with args against the compose file of service.
+func (r Runner) run(service DockermiTypes.ServiceScript, args ...string) error {
+	command := r.Command
+	if len(command) == 0 {
+		command = []string{compose.Default}
+	}
+	stdout, stderr := r.Stdout, r.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	c := script.Command{Env: r.Env, Args: append(append(append([]string(nil), command...), "-f", service.ComposeFile), args...)}
+	if r.DryRun {
+		_, err := fmt.Fprintln(stdout, c)
+		return err
+	}
+
+	cmd := exec.Command(c.Args[0], c.Args[1:]...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", c, err)
+	}
+	return nil
+}
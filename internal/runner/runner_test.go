@@ -0,0 +1,175 @@
+package runner_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/events"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/runner"
+	"github.com/mkhuda/dockermi/types"
+)
+
+// recorder is an events.Observer keeping every event.
+type recorder []events.Event
+
+func (r *recorder) Event(event events.Event) {
+	*r = append(*r, event)
+}
+
+func TestRunEvents(t *testing.T) {
+	binDir := t.TempDir()
+	fake := "#!/bin/sh\ncase \"$*\" in *\" web\") exit 3 ;; esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte(fake), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+
+	services := []types.ServiceScript{
+		{Order: "1", ServiceName: "db", ComposeFile: "/app/docker-compose.yml"},
+		{Order: "2", ServiceName: "api", ComposeFile: "/app/docker-compose.yml"},
+		{Order: "2", ServiceName: "web", ComposeFile: "/app/docker-compose.yml"},
+		{Order: "3", ServiceName: "worker", ComposeFile: "/app/docker-compose.yml"},
+	}
+
+	var recorded recorder
+	r := runner.Runner{
+		Command:  []string{filepath.Join(binDir, "docker-compose")},
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Observer: &recorded,
+		Wait: func(ctx context.Context, service types.ServiceScript) (string, error) {
+			return "healthy", nil
+		},
+	}
+	if err := r.Run(context.Background(), "up", services); err == nil || !strings.HasPrefix(err.Error(), "web: ") {
+		t.Fatalf("Expected web to fail, got: %v", err)
+	}
+
+	var sequence []string
+	for _, event := range recorded {
+		sequence = append(sequence, strings.TrimSpace(string(event.Type)+" "+event.Service))
+	}
+	expected := []string{
+		"plan-resolved",
+		"service-starting db", "service-healthy db", "phase-complete",
+		"service-starting api", "service-healthy api",
+		"service-starting web", "service-failed web",
+		"run-finished",
+	}
+	if strings.Join(sequence, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected events:\n%v\ngot:\n%v", expected, sequence)
+	}
+	if len(recorded[0].Phases) != 3 || recorded[len(recorded)-1].Error == "" {
+		t.Fatalf("Expected a plan of 3 phases and a failed run, got: %+v / %+v", recorded[0], recorded[len(recorded)-1])
+	}
+
+	// Down runs stop the services in reverse order
+	recorded = nil
+	r.Observer = &recorded
+	if err := r.Run(context.Background(), "down", services[:2]); err != nil {
+		t.Fatalf("Error running down: %v", err)
+	}
+	if recorded[1].Service != "api" || recorded[2].Health != "stopped" {
+		t.Fatalf("Expected api to be stopped first, got: %+v", recorded[1:3])
+	}
+
+}
+
+func TestRunSharedServices(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "compose.log")
+	fake := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte(fake), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+
+	services := []types.ServiceScript{
+		{Order: "1", ServiceName: "db", ComposeFile: "/app/docker-compose.yml", Shared: true},
+		{Order: "1", ServiceName: "cache", ComposeFile: "/app/docker-compose.yml", Shared: true},
+		{Order: "2", ServiceName: "api", ComposeFile: "/app/docker-compose.yml"},
+	}
+
+	var recorded recorder
+	r := runner.Runner{
+		Command:  []string{filepath.Join(binDir, "docker-compose")},
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Observer: &recorded,
+		Running: func(ctx context.Context, service types.ServiceScript) (bool, error) {
+			return service.ServiceName == "db", nil
+		},
+	}
+	if err := r.Run(context.Background(), "up", services); err != nil {
+		t.Fatalf("Error running up: %v", err)
+	}
+	// A shared service is not started again when it is already running,
+	// and it is left running on down
+	if err := r.Run(context.Background(), "down", services); err != nil {
+		t.Fatalf("Error running down: %v", err)
+	}
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Error reading compose log: %v", err)
+	}
+	expected := strings.Join([]string{
+		"-f /app/docker-compose.yml up -d cache",
+		"-f /app/docker-compose.yml up -d api",
+		"-f /app/docker-compose.yml stop api",
+	}, "\n") + "\n"
+	if string(log) != expected {
+		t.Fatalf("Expected compose calls:\n%v\ngot:\n%v", expected, string(log))
+	}
+	for _, event := range recorded {
+		if event.Service == "db" && event.Action == "up" && event.Type == events.ServiceHealthy && event.Health != "already running (shared)" {
+			t.Fatalf("Expected db to be reported as already running, got: %+v", event)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	service := func(order, name, file string) types.ServiceScript {
+		return types.ServiceScript{Order: order, ServiceName: name, ComposeFile: file}
+	}
+	reload := plan.Reload{
+		Recreate: []types.ServiceScript{
+			service("1", "db", "/app/docker-compose.yml"),
+			service("2", "api", "/app/docker-compose.yml"),
+			service("3", "worker", "/app/docker-compose.yml"),
+		},
+		Start: []types.ServiceScript{service("2", "search", "/search/docker-compose.yml")},
+		Stop:  []types.ServiceScript{service("1", "cache", "/cache/docker-compose.yml")},
+	}
+
+	// A fake docker-compose records its arguments instead of talking to Docker
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "compose.log")
+	fake := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte(fake), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+
+	var out bytes.Buffer
+	r := runner.Runner{Command: []string{filepath.Join(binDir, "docker-compose")}, Args: []string{"--build"}, Stdout: &out, Stderr: &out}
+	if err := r.Apply(reload); err != nil {
+		t.Fatalf("Error applying reload: %v\n%s", err, out.String())
+	}
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Error reading compose log: %v", err)
+	}
+	expected := strings.Join([]string{
+		"-f /cache/docker-compose.yml stop cache",
+		"-f /app/docker-compose.yml up -d --no-deps --force-recreate db --build",
+		"-f /app/docker-compose.yml up -d --no-deps --force-recreate api --build",
+		"-f /search/docker-compose.yml up -d --no-deps search --build",
+		"-f /app/docker-compose.yml up -d --no-deps --force-recreate worker --build",
+	}, "\n") + "\n"
+	if string(log) != expected {
+		t.Fatalf("Expected compose calls:\n%v\ngot:\n%v", expected, string(log))
+	}
+}
@@ -0,0 +1,218 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/plan"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// Data is the model passed to script templates.
+//
+//   - Header: the '# dockermi:' metadata comment lines, ending with a newline
+//   - ComposeCommand: the compose command line invoked for every service (e.g. docker-compose)
+//   - Podman: true when the compose tool runs containers with Podman
+//   - Engine: the engine the script was generated for (see Engine), nil for
+//     the engine selected by the environment
+//   - Flags: the dockermi flags the script was generated with
+//   - Services: the services in start order (ascending dockermi.order)
+//   - StopServices: the services in stop order (descending dockermi.order)
+//   - Phases: the services grouped by dockermi.order in start order; each phase
+//     has an Order and its Services
+//   - Groups: the dockermi.key groups sorted by Key; each group has a Key, a
+//     Target name, its Services in start order and its StopServices in stop order;
+//     the services of a group are Shared when they belong to other groups too
+//
+// Each service exposes Order, ServiceName, ComposeFile, Keys, Shared (true when
+// the service belongs to more than one dockermi.key group), Project (the compose
+// project name of its compose file), Target (a name unique
+// within the script, usable as a make target or function name) and After (the
+// Targets of the services that must run first: the previous phase in Services,
+// the next phase in StopServices). Templates can use the 'quote' function to
+// quote a value for the shell of the selected format.
+type Data struct {
+	Header         string
+	ComposeCommand string
+	Podman         bool
+	Engine         *Engine
+	Flags          []string
+	Services       []Service
+	StopServices   []Service
+	Phases         []Phase
+	Groups         []Group
+}
+
+// Service is a service as seen by templates.
+type Service struct {
+	DockermiTypes.ServiceScript
+	Project string
+	Target  string
+	After   []string
+}
+
+// Engine is the engine a script targets unless its environment already
+// selects one: the script sets the variable Name to Value only when none of
+// Variables (e.g. DOCKER_CONTEXT and DOCKER_HOST) is set.
+type Engine struct {
+	Name      string
+	Value     string
+	Variables []string
+}
+
+// Phase is a set of services sharing the same dockermi.order.
+type Phase struct {
+	Order    string
+	Services []Service
+}
+
+// Group is the set of services sharing a dockermi.key.
+type Group struct {
+	Key          string
+	Target       string // Target is Key made safe for target and function names
+	Services     []Service
+	StopServices []Service
+}
+
+// NewData builds the template data model for services, invoking the default
+// compose tool. Use UseCompose to select another one.
+func NewData(services DockermiTypes.ServiceScriptReturn, header Header) Data {
+	return newData(services, header, nil)
+}
+
+// newData builds the template data model for services, calling notify, when
+// not nil, with every service in start order once it is built.
+func newData(services DockermiTypes.ServiceScriptReturn, header Header, notify func(Service)) Data {
+	ordered := append([]DockermiTypes.ServiceScript(nil), services...)
+	plan.Sort(ordered)
+
+	targets := targetNames(ordered)
+	phases := plan.Phases(ordered)
+
+	projects := make(map[string]string)
+	newService := func(s DockermiTypes.ServiceScript, after []string) Service {
+		project, ok := projects[s.ComposeFile]
+		if !ok {
+			project = ProjectName(s.ComposeFile)
+			projects[s.ComposeFile] = project
+		}
+		return Service{ServiceScript: s, Project: project, Target: targets[plan.ID(s)], After: after}
+	}
+
+	data := Data{
+		Header:         header.String(),
+		ComposeCommand: "docker-compose",
+		Flags:          header.Flags,
+	}
+
+	groups := make(map[string][]Service)
+	for i, phase := range phases {
+		var after []string
+		if i > 0 {
+			after = phaseTargets(phases[i-1], targets)
+		}
+
+		p := Phase{Order: phase.Order}
+		for _, s := range phase.Services {
+			service := newService(s, after)
+			p.Services = append(p.Services, service)
+			data.Services = append(data.Services, service)
+			if notify != nil {
+				notify(service)
+			}
+			// Within a group a service is shared when other groups use it too
+			member := service
+			member.Shared = len(s.Keys) > 1
+			for _, key := range s.Keys {
+				groups[key] = append(groups[key], member)
+			}
+		}
+		data.Phases = append(data.Phases, p)
+	}
+
+	for i := len(phases) - 1; i >= 0; i-- {
+		var after []string
+		if i < len(phases)-1 {
+			after = phaseTargets(phases[i+1], targets)
+		}
+		for j := len(phases[i].Services) - 1; j >= 0; j-- {
+			s := phases[i].Services[j]
+			data.StopServices = append(data.StopServices, newService(s, after))
+		}
+	}
+
+	for key, services := range groups {
+		stop := make([]Service, len(services))
+		for i, service := range services {
+			stop[len(services)-1-i] = service
+		}
+		data.Groups = append(data.Groups, Group{Key: key, Target: slug(key), Services: services, StopServices: stop})
+	}
+	sort.Slice(data.Groups, func(i, j int) bool {
+		return data.Groups[i].Key < data.Groups[j].Key
+	})
+
+	return data
+}
+
+// UseCompose makes the script invoke tool instead of the default compose tool.
+func (d *Data) UseCompose(tool compose.Tool) {
+	d.ComposeCommand = tool.String()
+	d.Podman = tool.Podman
+}
+
+// UseTarget makes the script use the engine selected by target, given the
+// tool the script invokes. The zero target leaves the choice to the environment.
+func (d *Data) UseTarget(tool compose.Tool, target compose.Target) {
+	env := target.Env(tool)
+	if len(env) == 0 {
+		d.Engine = nil
+		return
+	}
+	context, host := tool.Variables()
+	variable := strings.SplitN(env[0], "=", 2)
+	d.Engine = &Engine{Name: variable[0], Value: variable[1], Variables: []string{context, host}}
+}
+
+// phaseTargets returns the target names of the services in phase.
+func phaseTargets(phase plan.Phase, targets map[string]string) []string {
+	names := make([]string, 0, len(phase.Services))
+	for _, service := range phase.Services {
+		names = append(names, targets[plan.ID(service)])
+	}
+	return names
+}
+
+// targetNames assigns every service a name made of lowercase letters, digits,
+// '-' and '_'. Services sharing a name (e.g. 'web' in two compose files) get a
+// numeric suffix in start order.
+func targetNames(services []DockermiTypes.ServiceScript) map[string]string {
+	names := make(map[string]string, len(services))
+	used := make(map[string]int)
+	for _, service := range services {
+		name := slug(service.ServiceName)
+		used[name]++
+		if used[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, used[name])
+		}
+		names[plan.ID(service)] = name
+	}
+	return names
+}
+
+// slug lowercases s and replaces every character other than letters, digits,
+// '-' and '_' with '-'.
+func slug(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, s)
+}
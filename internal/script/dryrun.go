@@ -0,0 +1,105 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mkhuda/dockermi/internal/plan"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+	"gopkg.in/yaml.v2"
+)
+
+// Command is a compose command a generated script runs for one service.
+type Command struct {
+	// Project is the compose project name the command applies to.
+	Project string
+	// Env holds the environment entries (NAME=value) the command runs with on
+	// top of the current environment.
+	Env  []string
+	Args []string
+	// Shared is true when the command starts a service shared with other
+	// groups, which the script skips when the service is already running.
+	Shared bool
+}
+
+// String returns the command as it would be typed in a shell.
+func (c Command) String() string {
+	quoted := make([]string, 0, len(c.Env)+len(c.Args))
+	for _, env := range c.Env {
+		variable := strings.SplitN(env, "=", 2)
+		quoted = append(quoted, variable[0]+"="+shellWord(variable[len(variable)-1]))
+	}
+	for _, arg := range c.Args {
+		quoted = append(quoted, shellWord(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellWord quotes arg unless it needs no quoting in a shell.
+func shellWord(arg string) string {
+	if arg == "" || plainArg.FindString(arg) != arg {
+		return shellQuote(arg)
+	}
+	return arg
+}
+
+// Commands returns the compose commands a script invoking composeCommand (e.g.
+// ["podman", "compose"]) runs for services for the given action ("up" or
+// "down"), with the passthrough args appended. services must be in start
+// order; they are stopped in reverse order. Shared services are left running
+// on down, so they have no command.
+func Commands(composeCommand []string, services []DockermiTypes.ServiceScript, action string, args []string) []Command {
+	verb := []string{"up", "-d"}
+	if action == "down" {
+		services = plan.Reverse(services)
+		verb = []string{"stop"}
+	}
+
+	projects := make(map[string]string)
+	commands := make([]Command, 0, len(services))
+	for _, service := range services {
+		if service.Shared && action == "down" {
+			continue
+		}
+		project, ok := projects[service.ComposeFile]
+		if !ok {
+			project = ProjectName(service.ComposeFile)
+			projects[service.ComposeFile] = project
+		}
+
+		command := append(append([]string(nil), composeCommand...), "-f", service.ComposeFile)
+		command = append(command, verb...)
+		command = append(command, service.ServiceName)
+		commands = append(commands, Command{Project: project, Args: append(command, args...), Shared: service.Shared})
+	}
+	return commands
+}
+
+// plainArg matches arguments that need no quoting in a shell.
+var plainArg = regexp.MustCompile(`[A-Za-z0-9_@%+=:,./-]+`)
+
+// invalidProjectChars matches the characters docker compose drops from project names.
+var invalidProjectChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// ProjectName resolves the compose project name used for composeFile the way
+// docker compose does: COMPOSE_PROJECT_NAME, then the top-level name of the
+// compose file, then the name of the directory holding it.
+func ProjectName(composeFile string) string {
+	if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return name
+	}
+
+	if content, err := os.ReadFile(composeFile); err == nil {
+		var file struct {
+			Name string `yaml:"name"`
+		}
+		if yaml.Unmarshal(content, &file) == nil && file.Name != "" {
+			return file.Name
+		}
+	}
+
+	dir := filepath.Base(filepath.Dir(composeFile))
+	return invalidProjectChars.ReplaceAllString(strings.ToLower(dir), "")
+}
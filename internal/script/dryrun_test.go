@@ -0,0 +1,79 @@
+package script_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestDryRun(t *testing.T) {
+	projectDir := t.TempDir()
+	named := filepath.Join(projectDir, "named", "docker-compose.yml")
+	unnamed := filepath.Join(projectDir, "My App", "docker-compose.yml")
+	for path, content := range map[string]string{
+		named:   "name: shop\nservices:\n  db:\n    image: postgres\n",
+		unnamed: "services:\n  web:\n    image: nginx\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing compose file: %v", err)
+		}
+	}
+
+	services := []types.ServiceScript{
+		{Order: "1", ServiceName: "db", ComposeFile: named},
+		{Order: "2", ServiceName: "web", ComposeFile: unnamed},
+	}
+
+	var up []string
+	for _, c := range script.Commands([]string{"docker-compose"}, services, "up", []string{"--build"}) {
+		up = append(up, c.Project+": "+c.String())
+	}
+	expectedUp := []string{
+		"shop: docker-compose -f " + named + " up -d db --build",
+		"myapp: docker-compose -f \"" + unnamed + "\" up -d web --build",
+	}
+	if strings.Join(up, "\n") != strings.Join(expectedUp, "\n") {
+		t.Fatalf("Expected up commands:\n%v\ngot:\n%v", strings.Join(expectedUp, "\n"), strings.Join(up, "\n"))
+	}
+
+	down := script.Commands([]string{"docker-compose"}, services, "down", nil)
+	if len(down) != 2 || down[0].String() != "docker-compose -f \""+unnamed+"\" stop web" {
+		t.Fatalf("Expected web to be stopped first, got: %v", down)
+	}
+
+	// A shared service is started only when it is not running and is left
+	// running on down
+	services[0].Shared = true
+	if up := script.Commands([]string{"docker-compose"}, services, "up", nil); !up[0].Shared || up[1].Shared {
+		t.Fatalf("Expected only the db command to be shared, got: %+v", up)
+	}
+	if down := script.Commands([]string{"docker-compose"}, services, "down", nil); len(down) != 1 || down[0].Args[len(down[0].Args)-1] != "web" {
+		t.Fatalf("Expected only web to be stopped, got: %v", down)
+	}
+
+	c := script.Command{Env: compose.Target{Host: "ssh://deploy@build"}.Env(compose.Tools[0]), Args: []string{"docker-compose", "up"}}
+	if c.String() != "DOCKER_HOST=ssh://deploy@build docker-compose up" {
+		t.Fatalf("Unexpected command: %s", c)
+	}
+
+	old := []byte("#!/bin/bash\na\nb\nc\n")
+	new := []byte("#!/bin/bash\na\nB\nc\n")
+	expectedDiff := "--- old\n+++ new\n@@ -1,4 +1,4 @@\n #!/bin/bash\n a\n-b\n+B\n c\n"
+	if diff := script.Diff("old", "new", old, new); diff != expectedDiff {
+		t.Fatalf("Expected diff:\n%v\ngot:\n%v", expectedDiff, diff)
+	}
+	if diff := script.Diff("old", "new", old, old); diff != "" {
+		t.Fatalf("Expected no diff for equal scripts, got:\n%v", diff)
+	}
+	if diff := script.Diff("/dev/null", "new", nil, []byte("a\n")); !strings.Contains(diff, "@@ -0,0 +1 @@\n+a\n") {
+		t.Fatalf("Expected a new file diff, got:\n%v", diff)
+	}
+}
@@ -0,0 +1,98 @@
+package script
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed templates/bash.tmpl
+var bashTemplate string
+
+//go:embed templates/sh.tmpl
+var shTemplate string
+
+//go:embed templates/powershell.tmpl
+var powershellTemplate string
+
+//go:embed templates/make.tmpl
+var makeTemplate string
+
+//go:embed templates/just.tmpl
+var justTemplate string
+
+// Format describes a kind of generated script.
+type Format struct {
+	Name      string
+	Extension string // Extension is appended to the script name, e.g. ".sh"
+	Template  string // Template is the built-in template text
+	quote     func(string) string
+	delims    [2]string // delims overrides the {{ }} action delimiters when set
+}
+
+// formats lists the supported script formats by name.
+var formats = map[string]Format{
+	"bash":       {Name: "bash", Extension: ".sh", Template: bashTemplate, quote: shellQuote},
+	"powershell": {Name: "powershell", Extension: ".ps1", Template: powershellTemplate, quote: powershellQuote},
+	"sh":         {Name: "sh", Extension: ".sh", Template: shTemplate, quote: shellQuote},
+	"make":       {Name: "make", Extension: ".mk", Template: makeTemplate, quote: makeQuote},
+	// just uses {{ }} for its own interpolation, so its templates use [[ ]] instead
+	"just": {Name: "just", Extension: ".just", Template: justTemplate, quote: shellQuote, delims: [2]string{"[[", "]]"}},
+}
+
+// DefaultFormat is used when no --format is given.
+const DefaultFormat = "bash"
+
+// LookupFormat returns the format registered under name. An empty name selects DefaultFormat.
+func LookupFormat(name string) (Format, error) {
+	if name == "" {
+		name = DefaultFormat
+	}
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown format: %s (supported: %s)", name, strings.Join(FormatNames(), ", "))
+	}
+	return format, nil
+}
+
+// FormatNames returns the names of all supported formats.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Extensions returns the script extensions of all supported formats.
+func Extensions() []string {
+	var extensions []string
+	seen := make(map[string]bool)
+	for _, name := range FormatNames() {
+		if ext := formats[name].Extension; !seen[ext] {
+			seen[ext] = true
+			extensions = append(extensions, ext)
+		}
+	}
+	return extensions
+}
+
+// shellQuote double-quotes s for POSIX shells, escaping characters that are special inside double quotes.
+func shellQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// powershellQuote double-quotes s for PowerShell, escaping with backticks.
+func powershellQuote(s string) string {
+	replacer := strings.NewReplacer("`", "``", `"`, "`\"", "$", "`$")
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// makeQuote quotes s for a make recipe line, which is run by the shell after
+// make expanded its own '$' references.
+func makeQuote(s string) string {
+	return strings.ReplaceAll(shellQuote(s), "$", "$$")
+}
@@ -0,0 +1,237 @@
+package script
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/plan"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// headerPrefix marks the structured comment lines written at the top of generated scripts.
+const headerPrefix = "# dockermi:"
+
+// Source is a compose file used to generate a script, with its SHA-256 at generation time.
+type Source struct {
+	Path   string
+	SHA256 string
+}
+
+// Header is the metadata embedded in every generated script.
+type Header struct {
+	Version   string
+	Generated time.Time
+	Root      string
+	Key       string // Key is the dockermi.key of a group script, empty for dockermi.sh
+	Flags     []string
+	Sources   []Source
+	// Files are the compose files found under Root when the script was
+	// generated, including the ones none of its services come from.
+	Files []string
+	// Orders maps the plan ID of every service of the script to its
+	// dockermi.order, which the commands of the script do not show.
+	Orders map[string]string
+}
+
+// NewHeader builds the header for a script generated from services, hashing
+// every compose file the services come from.
+func NewHeader(version, root, key string, flags []string, services DockermiTypes.ServiceScriptReturn) (Header, error) {
+	header := Header{
+		Version:   version,
+		Generated: time.Now().UTC(),
+		Root:      root,
+		Key:       key,
+		Flags:     flags,
+	}
+
+	header.Orders = make(map[string]string, len(services))
+	seen := make(map[string]bool)
+	for _, service := range services {
+		header.Orders[plan.ID(service)] = service.Order
+		if seen[service.ComposeFile] {
+			continue
+		}
+		seen[service.ComposeFile] = true
+
+		sum, err := hashFile(service.ComposeFile)
+		if err != nil {
+			return Header{}, err
+		}
+		header.Sources = append(header.Sources, Source{Path: service.ComposeFile, SHA256: sum})
+	}
+	sort.Slice(header.Sources, func(i, j int) bool {
+		return header.Sources[i].Path < header.Sources[j].Path
+	})
+
+	return header, nil
+}
+
+// HasFlag reports whether the script was generated with the given flag.
+func (h Header) HasFlag(flag string) bool {
+	for _, f := range h.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// FlagValue returns the value of a "--name=value" flag the script was generated with.
+func (h Header) FlagValue(name string) string {
+	for _, f := range h.Flags {
+		if strings.HasPrefix(f, name+"=") {
+			return strings.TrimPrefix(f, name+"=")
+		}
+	}
+	return ""
+}
+
+// ChangedSources returns the compose files that were modified or removed since
+// the script was generated.
+func (h Header) ChangedSources() []string {
+	var changed []string
+	for _, source := range h.Sources {
+		sum, err := hashFile(source.Path)
+		if err != nil || sum != source.SHA256 {
+			changed = append(changed, source.Path)
+		}
+	}
+	return changed
+}
+
+// AddedFiles returns the compose files of current that were not found under
+// Root when the script was generated. Scripts that recorded no files (e.g.
+// generated by an older version) report none.
+func (h Header) AddedFiles(current []string) []string {
+	if len(h.Files) == 0 {
+		return nil
+	}
+	known := make(map[string]bool, len(h.Files))
+	for _, path := range h.Files {
+		known[path] = true
+	}
+
+	var added []string
+	for _, path := range current {
+		if !known[path] {
+			added = append(added, path)
+		}
+	}
+	return added
+}
+
+// String renders the header as shell comment lines.
+func (h Header) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sversion %s\n", headerPrefix, h.Version)
+	fmt.Fprintf(&b, "%sgenerated %s\n", headerPrefix, h.Generated.Format(time.RFC3339))
+	fmt.Fprintf(&b, "%sroot %s\n", headerPrefix, h.Root)
+	if h.Key != "" {
+		fmt.Fprintf(&b, "%skey %s\n", headerPrefix, h.Key)
+	}
+	if len(h.Flags) > 0 {
+		// Flags are a JSON array so values containing spaces survive
+		flags, _ := json.Marshal(h.Flags)
+		fmt.Fprintf(&b, "%sflags %s\n", headerPrefix, flags)
+	}
+	for _, source := range h.Sources {
+		fmt.Fprintf(&b, "%ssource %s  %s\n", headerPrefix, source.SHA256, source.Path)
+	}
+	for _, path := range h.Files {
+		fmt.Fprintf(&b, "%sfile %s\n", headerPrefix, path)
+	}
+	ids := make([]string, 0, len(h.Orders))
+	for id := range h.Orders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&b, "%sorder %s  %s\n", headerPrefix, h.Orders[id], id)
+	}
+	return b.String()
+}
+
+// ReadHeader parses the header of a generated script. The returned bool is false
+// when the script has no dockermi header (e.g. written by hand or by an older version).
+func ReadHeader(scriptPath string) (Header, bool, error) {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return Header{}, false, err
+	}
+	defer file.Close()
+
+	var header Header
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, headerPrefix) {
+			// The header is a contiguous block near the top of the script
+			if found && !strings.HasPrefix(line, "#") {
+				break
+			}
+			continue
+		}
+		found = true
+
+		field, value := line[len(headerPrefix):], ""
+		if i := strings.IndexByte(field, ' '); i >= 0 {
+			field, value = field[:i], field[i+1:]
+		}
+
+		switch field {
+		case "version":
+			header.Version = value
+		case "generated":
+			header.Generated, _ = time.Parse(time.RFC3339, value)
+		case "root":
+			header.Root = value
+		case "key":
+			header.Key = value
+		case "flags":
+			// Older scripts joined the flags with spaces
+			if err := json.Unmarshal([]byte(value), &header.Flags); err != nil {
+				header.Flags = strings.Fields(value)
+			}
+		case "source":
+			parts := strings.SplitN(value, "  ", 2)
+			if len(parts) == 2 {
+				header.Sources = append(header.Sources, Source{SHA256: parts[0], Path: parts[1]})
+			}
+		case "file":
+			header.Files = append(header.Files, value)
+		case "order":
+			parts := strings.SplitN(value, "  ", 2)
+			if len(parts) == 2 {
+				if header.Orders == nil {
+					header.Orders = make(map[string]string)
+				}
+				header.Orders[parts[1]] = parts[0]
+			}
+		}
+	}
+	return header, found, scanner.Err()
+}
+
+// hashFile returns the hex encoded SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,64 @@
+package script_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestScriptHeader(t *testing.T) {
+	projectDir := t.TempDir()
+	composeFile := filepath.Join(projectDir, "docker-compose.yml")
+	if err := os.WriteFile(composeFile, []byte("services:\n  web:\n    image: nginx\n"), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	services := types.ServiceScriptReturn{{Order: "1", ServiceName: "web", ComposeFile: composeFile}}
+	template := filepath.Join(projectDir, "My Templates", "dockermi.tmpl")
+	header, err := script.NewHeader("test", projectDir, "", []string{"--force", "--template=" + template}, services)
+	if err != nil {
+		t.Fatalf("Error creating header: %v", err)
+	}
+	header.Files = []string{composeFile}
+
+	scriptPath := filepath.Join(projectDir, "dockermi.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\n\n"+header.String()+"\nstart_services() {\n}\n"), 0755); err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+
+	read, found, err := script.ReadHeader(scriptPath)
+	if err != nil || !found {
+		t.Fatalf("Expected header to be found: %v", err)
+	}
+	if read.Root != projectDir || read.Version != "test" || !read.HasFlag("--force") || len(read.Sources) != 1 {
+		t.Fatalf("Unexpected header: %+v", read)
+	}
+	if read.FlagValue("--template") != template {
+		t.Fatalf("Expected the template flag to keep its spaces, got: %q", read.FlagValue("--template"))
+	}
+	if changed := read.ChangedSources(); len(changed) != 0 {
+		t.Fatalf("Expected no changed sources, got: %v", changed)
+	}
+	if order := read.Orders[composeFile+"#web"]; order != "1" {
+		t.Fatalf("Expected the order of web to be recorded, got: %q", order)
+	}
+
+	// A compose file added next to the recorded ones is reported
+	added := filepath.Join(projectDir, "api", "docker-compose.yml")
+	if files := read.AddedFiles([]string{composeFile, added}); len(files) != 1 || files[0] != added {
+		t.Fatalf("Expected %v to be reported as added, got: %v", added, files)
+	}
+	if files := (script.Header{}).AddedFiles([]string{added}); len(files) != 0 {
+		t.Fatalf("Expected a header without files to report none, got: %v", files)
+	}
+
+	if err := os.WriteFile(composeFile, []byte("services:\n  web:\n    image: nginx:alpine\n"), 0644); err != nil {
+		t.Fatalf("Error updating compose file: %v", err)
+	}
+	if changed := read.ChangedSources(); len(changed) != 1 || changed[0] != composeFile {
+		t.Fatalf("Expected %v to be reported as changed, got: %v", composeFile, changed)
+	}
+}
@@ -0,0 +1,192 @@
+package script_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/types"
+)
+
+// bashisms are constructs that work in bash but not in POSIX shells such as dash or ash.
+var bashisms = []struct {
+	pattern *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`^#!.*bash`), "bash shebang"},
+	{regexp.MustCompile(`\[\[`), "[[ test expression"},
+	{regexp.MustCompile(`^\s*function\s`), "function keyword"},
+	{regexp.MustCompile(`\btrap\b.*\bSIG[A-Z]+`), "SIG prefix in trap signal name"},
+	{regexp.MustCompile(`\[ [^]]*==`), "== inside [ ]"},
+	{regexp.MustCompile(`^\s*local\s`), "local variable"},
+	{regexp.MustCompile(`\$'`), "$'...' quoting"},
+	{regexp.MustCompile(`<\(`), "process substitution"},
+	{regexp.MustCompile(`&>`), "&> redirection"},
+	{regexp.MustCompile(`^\s*source\s`), "source builtin"},
+	{regexp.MustCompile(`\w+=\(`), "array assignment"},
+	{regexp.MustCompile(`\(\(`), "arithmetic command"},
+	{regexp.MustCompile(`\becho -e\b`), "echo -e"},
+	{regexp.MustCompile(`\$(RANDOM|BASH_\w+|PIPESTATUS)`), "bash-only variable"},
+}
+
+// lintPOSIX returns a finding for every line of content using a bashism.
+func lintPOSIX(content string) []string {
+	var findings []string
+	for i, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") && i > 0 {
+			continue
+		}
+		for _, b := range bashisms {
+			if b.pattern.MatchString(line) {
+				findings = append(findings, fmt.Sprintf("line %d: %s: %s", i+1, b.message, line))
+			}
+		}
+	}
+	return findings
+}
+
+// renderFormat renders services with the built-in template of the given format.
+func renderFormat(t *testing.T, name string, services types.ServiceScriptReturn) string {
+	t.Helper()
+	format, err := script.LookupFormat(name)
+	if err != nil {
+		t.Fatalf("Error looking up format: %v", err)
+	}
+	tmpl, err := script.LoadTemplate("", format)
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := script.Render(&buf, tmpl, script.NewData(services, script.Header{Version: "test"})); err != nil {
+		t.Fatalf("Error rendering script: %v", err)
+	}
+	return buf.String()
+}
+
+// fixturesDir returns the absolute path of the test/ folder. Other tests change
+// the working directory, so it is resolved from the module root.
+func fixturesDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Error getting current working directory: %v", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, "test")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatalf("Could not find the module root")
+		}
+		dir = parent
+	}
+}
+
+// posixFixtures returns the plans generated from the compose files in test/.
+func posixFixtures(t *testing.T) map[string]types.ServiceScriptReturn {
+	t.Helper()
+	root := fixturesDir(t)
+
+	fixtures := make(map[string]types.ServiceScriptReturn)
+	for name, force := range map[string]bool{"labels": false, "force": true} {
+		services, err := dockercompose.FindServices(root, force)
+		if err != nil {
+			t.Fatalf("Error finding services: %v", err)
+		}
+		fixtures[name] = services
+	}
+
+	groups, err := dockercompose.FindServicesWithKey(root)
+	if err != nil {
+		t.Fatalf("Error finding services: %v", err)
+	}
+	for key, services := range groups {
+		// Mark the services as shared to cover the running-service guard as well
+		for i := range services {
+			services[i].Shared = true
+		}
+		fixtures["key-"+key] = services
+	}
+	return fixtures
+}
+
+func TestPOSIXLinterDetectsBashisms(t *testing.T) {
+	services := types.ServiceScriptReturn{{Order: "1", ServiceName: "web", ComposeFile: "/srv/docker-compose.yml"}}
+	if findings := lintPOSIX(renderFormat(t, "bash", services)); len(findings) == 0 {
+		t.Fatalf("Expected the bash template to be reported by the POSIX linter")
+	}
+}
+
+func TestPOSIXScriptFixtures(t *testing.T) {
+	for name, services := range posixFixtures(t) {
+		content := renderFormat(t, "sh", services)
+
+		if findings := lintPOSIX(content); len(findings) > 0 {
+			t.Errorf("[%v] Generated sh script is not POSIX:\n%v", name, strings.Join(findings, "\n"))
+		}
+
+		if _, err := exec.LookPath("sh"); err != nil {
+			continue
+		}
+		scriptPath := filepath.Join(t.TempDir(), "dockermi.sh")
+		if err := os.WriteFile(scriptPath, []byte(content), 0755); err != nil {
+			t.Fatalf("Error writing script: %v", err)
+		}
+		if out, err := exec.Command("sh", "-n", scriptPath).CombinedOutput(); err != nil {
+			t.Errorf("[%v] sh -n failed: %v\n%s", name, err, out)
+		}
+	}
+}
+
+func TestPOSIXScriptRuns(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// A fake docker-compose records its arguments instead of talking to Docker
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "compose.log")
+	fake := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte(fake), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	services := types.ServiceScriptReturn{
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml"},
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml", Shared: true},
+	}
+	scriptPath := filepath.Join(t.TempDir(), "dockermi.sh")
+	if err := os.WriteFile(scriptPath, []byte(renderFormat(t, "sh", services)), 0755); err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+
+	for _, action := range []string{"up", "down"} {
+		if out, err := exec.Command("sh", scriptPath, action, "--timeout", "5").CombinedOutput(); err != nil {
+			t.Fatalf("sh %v failed: %v\n%s", action, err, out)
+		}
+	}
+
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Error reading compose log: %v", err)
+	}
+	expected := strings.Join([]string{
+		"-f /srv/db/docker-compose.yml ps --services --filter status=running",
+		"-f /srv/db/docker-compose.yml up -d db --timeout 5",
+		"-f /srv/web/docker-compose.yml up -d web --timeout 5",
+		// The shared db is left running for the other groups
+		"-f /srv/web/docker-compose.yml stop web --timeout 5",
+	}, "\n") + "\n"
+	if string(log) != expected {
+		t.Fatalf("Expected compose calls:\n%v\ngot:\n%v", expected, string(log))
+	}
+}
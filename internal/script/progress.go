@@ -0,0 +1,49 @@
+package script
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Observer is notified while a script is generated. It is optional: nothing is
+// reported when Options.Observer is nil, which is what library users get.
+type Observer interface {
+	// Start is called once with the number of services in the script.
+	Start(total int)
+	// Service is called for every service of the script, in start order, as
+	// the script data of the service is built.
+	Service(service Service)
+	// Done is called once the script has been written.
+	Done()
+}
+
+// progressBar is an Observer drawing a progress bar, for use on a terminal.
+type progressBar struct {
+	w   io.Writer
+	bar *progressbar.ProgressBar
+}
+
+// NewProgressBar returns an Observer drawing a progress bar to w.
+func NewProgressBar(w io.Writer) Observer {
+	return &progressBar{w: w}
+}
+
+func (p *progressBar) Start(total int) {
+	p.bar = progressbar.NewOptions(total,
+		progressbar.OptionSetWriter(p.w),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+	)
+}
+
+func (p *progressBar) Service(service Service) {
+	p.bar.Describe(service.ServiceName)
+	p.bar.Add(1)
+}
+
+func (p *progressBar) Done() {
+	p.bar.Finish()
+	fmt.Fprintln(p.w)
+}
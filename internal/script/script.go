@@ -1,101 +1,215 @@
 package script
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"sort"
-	"time"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
 
-	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/plan"
 	DockermiTypes "github.com/mkhuda/dockermi/types"
-	"github.com/schollz/progressbar/v3"
 )
 
-// CreateDockermiScript generates the dockermi.sh script based on the provided services.
-func CreateDockermiScript(scriptPath string, services DockermiTypes.ServiceScriptReturn) error {
-	dockermiScript, err := os.Create(scriptPath)
+// Options controls how a script is generated.
+type Options struct {
+	// Template is the path to a custom text/template file. When empty the
+	// built-in template of Format is used.
+	Template string
+	// Format is the name of the script format, e.g. "bash" or "powershell".
+	Format string
+	// Overwrite allows replacing an existing script that has no dockermi header,
+	// e.g. one written or heavily edited by hand.
+	Overwrite bool
+	// Observer is notified of the generation progress. It may be nil.
+	Observer Observer
+	// Compose is the name of the compose tool the script invokes, e.g.
+	// "podman-compose". The default tool is used when it is empty.
+	Compose string
+	// Target is the engine the script uses unless its environment selects one.
+	Target compose.Target
+}
+
+// LoadTemplate parses the template at path, or the built-in template of format
+// when path is empty.
+func LoadTemplate(path string, format Format) (*template.Template, error) {
+	name, text := format.Name+".tmpl", format.Template
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name, text = filepath.Base(path), string(content)
+	}
+
+	tmpl := template.New(name).Funcs(template.FuncMap{"quote": format.quote})
+	if format.delims[0] != "" {
+		tmpl = tmpl.Delims(format.delims[0], format.delims[1])
+	}
+	tmpl, err := tmpl.Parse(text)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid template %s: %w", name, err)
 	}
-	defer dockermiScript.Close()
+	return tmpl, nil
+}
 
-	dockermiScript.WriteString("#!/bin/bash\n\n")
-	dockermiScript.WriteString("# Usage: dockermi [up|down] [options]\n\n")
+// Render executes tmpl with data and writes the script to w.
+func Render(w io.Writer, tmpl *template.Template, data Data) error {
+	return tmpl.Execute(w, data)
+}
 
-	// Sort services for starting (ascending order)
-	sort.Slice(services, func(i, j int) bool {
-		return services[i].Order < services[j].Order
-	})
+// Generate renders the script for services without writing it.
+func Generate(services DockermiTypes.ServiceScriptReturn, header Header, opts Options) ([]byte, error) {
+	return generate(services, header, opts, nil)
+}
 
-	// Generate start_services function
-	dockermiScript.WriteString("start_services() {\n")
+// generate renders the script for services, notifying observer, when not nil,
+// as the data of every service is built.
+func generate(services DockermiTypes.ServiceScriptReturn, header Header, opts Options, observer Observer) ([]byte, error) {
+	format, err := LookupFormat(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := LoadTemplate(opts.Template, format)
+	if err != nil {
+		return nil, err
+	}
+	tool, err := compose.Lookup(opts.Compose)
+	if err != nil {
+		return nil, err
+	}
+
+	var notify func(Service)
+	if observer != nil {
+		observer.Start(len(services))
+		notify = observer.Service
+	}
+	data := newData(services, header, notify)
+	data.UseCompose(tool)
+	data.UseTarget(tool, opts.Target)
+
+	var buf bytes.Buffer
+	if err := Render(&buf, tmpl, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-	// Create a progress bar
-	bar := progressbar.New(len(services))
+// CreateDockermiScript generates the dockermi.sh script based on the provided services.
+// The header is written right after the shebang so the script records how it was generated.
+// An existing script is kept as scriptPath.bak and replaced atomically; a script without
+// a dockermi header is only replaced when opts.Overwrite is set.
+func CreateDockermiScript(scriptPath string, services DockermiTypes.ServiceScriptReturn, header Header, opts Options) error {
+	content, err := generate(services, header, opts, opts.Observer)
+	if err != nil {
+		return err
+	}
+
+	if err := backupScript(scriptPath, opts.Overwrite); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(scriptPath, content, 0755); err != nil {
+		return err
+	}
 
-	for _, service := range services {
-		dockermiScript.WriteString(fmt.Sprintf("    echo \"Starting %s...\"\n", service.ServiceName))
-		dockermiScript.WriteString(fmt.Sprintf("    docker-compose -f \"%s\" up -d \"%s\" \"$@\"\n", service.ComposeFile, service.ServiceName)) // Pass additional options and specify the service name
-		color.Cyan("\n Creating script for %v", service.ServiceName)
-		bar.Add(1)
+	if opts.Observer != nil {
+		opts.Observer.Done()
+	}
+	return nil
+}
 
-		time.Sleep(500 * time.Millisecond)
+// backupScript copies an existing script to scriptPath.bak before it is replaced.
+// It refuses to touch a script lacking the dockermi header unless overwrite is set.
+func backupScript(scriptPath string, overwrite bool) error {
+	previous, err := os.ReadFile(scriptPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
-	dockermiScript.WriteString("}\n\n")
 
-	// Generate stop_services function (descending order)
-	dockermiScript.WriteString("stop_services() {\n")
-	sort.Slice(services, func(i, j int) bool {
-		return services[i].Order > services[j].Order
-	})
-	for _, service := range services {
-		dockermiScript.WriteString(fmt.Sprintf("    echo \"Stopping %s...\"\n", service.ServiceName))
-		dockermiScript.WriteString(fmt.Sprintf("    docker-compose -f \"%s\" stop \"%s\" \"$@\"\n", service.ComposeFile, service.ServiceName))
-		bar.Add(1)
-		time.Sleep(500 * time.Millisecond) // Simulate delay for demonstration
+	if _, found, err := ReadHeader(scriptPath); err != nil {
+		return err
+	} else if !found && !overwrite {
+		return fmt.Errorf("refusing to overwrite %s: it has no dockermi header and may contain hand edits (use --overwrite to replace it)", scriptPath)
 	}
-	dockermiScript.WriteString("}\n\n")
 
-	// Add signal trap and main logic to call the appropriate function based on the argument
-	dockermiScript.WriteString(`cleanup() {
-    echo "Caught interrupt signal. Stopping services..."
-    exit 0
+	return writeFileAtomic(scriptPath+".bak", previous, 0644)
 }
 
-# Register the cleanup function to be called on SIGINT (Ctrl+C)
-trap cleanup SIGINT
-
-if [ "$#" -lt 1 ]; then
-    echo "Invalid argument!"
-    echo "Usage: $0 [up|down] [options]"
-    exit 1
-fi
-
-ACTION=$1
-shift
-
-case "$ACTION" in
-    up)
-        start_services "$@"
-        ;;
-    down)
-        stop_services "$@"
-        ;;
-    *)
-        echo "Invalid argument: $ACTION"
-        echo "Usage: $0 [up|down] [options]"
-        exit 1
-        ;;
-esac
-`)
-
-	// Make the dockermi.sh script executable (Unix systems)
-	if err := os.Chmod(scriptPath, 0755); err != nil {
-		color.Red("Error making the script executable: %v", err)
+// writeFileAtomic writes content to a temporary file next to path and renames
+// it into place, so that path never holds a partially written file.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
 		return err
 	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // no-op once the file has been renamed
 
-	color.Unset()
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	// Make the script executable (Unix systems)
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
 
-	return nil
+// startLine matches the compose up commands written by the default template.
+var startLine = regexp.MustCompile(` -f "([^"]*)" up -d "([^"]*)"`)
+
+// ReadScriptServices parses a previously generated script and returns its
+// services in start order. The start commands are read from the start function
+// of shell scripts, or from the per-service up-<service> targets of make and
+// just files. The Order field is read from the header, and left empty for
+// scripts generated by older versions, which did not record it.
+func ReadScriptServices(scriptPath string) ([]DockermiTypes.ServiceScript, error) {
+	header, _, err := ReadHeader(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var services []DockermiTypes.ServiceScript
+	inStart := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "start_services() {", line == "function Start-Services {", strings.HasPrefix(line, "up-"):
+			inStart = true
+		case line == "}", line == "":
+			inStart = false
+		case inStart:
+			if match := startLine.FindStringSubmatch(line); match != nil {
+				service := DockermiTypes.ServiceScript{
+					ComposeFile: match[1],
+					ServiceName: match[2],
+				}
+				service.Order = header.Orders[plan.ID(service)]
+				services = append(services, service)
+			}
+		}
+	}
+	return services, scanner.Err()
 }
@@ -0,0 +1,380 @@
+package script_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestScriptTemplate(t *testing.T) {
+	services := types.ServiceScriptReturn{
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml"},
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml"},
+		{Order: "1", ServiceName: "cache", ComposeFile: "/srv/db/docker-compose.yml"},
+	}
+	data := script.NewData(services, script.Header{Version: "test"})
+
+	bash, err := script.LookupFormat("bash")
+	if err != nil {
+		t.Fatalf("Error looking up format: %v", err)
+	}
+	tmpl, err := script.LoadTemplate("", bash)
+	if err != nil {
+		t.Fatalf("Error loading default template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := script.Render(&buf, tmpl, data); err != nil {
+		t.Fatalf("Error rendering default template: %v", err)
+	}
+	if !strings.Contains(buf.String(), `docker-compose -f "/srv/db/docker-compose.yml" up -d "cache" "$@"`) {
+		t.Fatalf("Expected default template to start cache, got:\n%v", buf.String())
+	}
+
+	custom := filepath.Join(t.TempDir(), "custom.tmpl")
+	content := `{{range .Phases}}phase {{.Order}}:{{range .Services}} {{quote .ServiceName}}{{end}}
+{{end}}`
+	if err := os.WriteFile(custom, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing template: %v", err)
+	}
+	if tmpl, err = script.LoadTemplate(custom, bash); err != nil {
+		t.Fatalf("Error loading custom template: %v", err)
+	}
+	buf.Reset()
+	if err := script.Render(&buf, tmpl, data); err != nil {
+		t.Fatalf("Error rendering custom template: %v", err)
+	}
+	expected := "phase 1: \"cache\" \"db\"\nphase 2: \"web\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestPowerShellScript(t *testing.T) {
+	services := types.ServiceScriptReturn{
+		{Order: "2", ServiceName: "web", ComposeFile: `C:\srv\web\docker-compose.yml`},
+		{Order: "1", ServiceName: "db", ComposeFile: `C:\srv\$db\docker-compose.yml`, Shared: true},
+	}
+
+	powershell, err := script.LookupFormat("powershell")
+	if err != nil {
+		t.Fatalf("Error looking up format: %v", err)
+	}
+	tmpl, err := script.LoadTemplate("", powershell)
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "dockermi"+powershell.Extension)
+	file, err := os.Create(scriptPath)
+	if err != nil {
+		t.Fatalf("Error creating script: %v", err)
+	}
+	err = script.Render(file, tmpl, script.NewData(services, script.Header{Version: "test"}))
+	file.Close()
+	if err != nil {
+		t.Fatalf("Error rendering script: %v", err)
+	}
+
+	content, err := os.ReadFile(scriptPath)
+	if err != nil {
+		t.Fatalf("Error reading script: %v", err)
+	}
+	for _, expected := range []string{
+		"function Start-Services {",
+		`& docker-compose -f "C:\srv\web\docker-compose.yml" up -d "web" @Options`,
+		`ps --services --filter "status=running"`,
+		"`$db",
+		`"stop" { Stop-Services -Options $Options }`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("Expected script to contain %q, got:\n%v", expected, string(content))
+		}
+	}
+
+	read, err := script.ReadScriptServices(scriptPath)
+	if err != nil {
+		t.Fatalf("Error reading script services: %v", err)
+	}
+	if len(read) != 2 || read[0].ServiceName != "db" || read[1].ServiceName != "web" {
+		t.Fatalf("Expected db and web in start order, got: %+v", read)
+	}
+}
+
+func TestMakeAndJustFormats(t *testing.T) {
+	services := types.ServiceScriptReturn{
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml", Keys: []string{"api", "admin"}},
+		{Order: "1", ServiceName: "Cache", ComposeFile: "/srv/db/docker-compose.yml"},
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml", Keys: []string{"api"}},
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/admin/docker-compose.yml"},
+	}
+
+	makefile := renderFormat(t, "make", services)
+	for _, expected := range []string{
+		"up: up-cache up-db up-web up-web-2\n",
+		"up-web-2: up-cache up-db\n\t@echo \"Starting web...\"\n\t$(COMPOSE) -f \"/srv/web/docker-compose.yml\" up -d \"web\" $(ARGS)\n",
+		"down-db: down-web down-web-2\n",
+		"group-api-up:\n\t@if $(COMPOSE) -f \"/srv/db/docker-compose.yml\" ps --services --filter \"status=running\" 2>/dev/null | grep -qx \"db\"; then \\\n",
+		"group-api-down:\n\t@echo \"Stopping web...\"",
+		"restart:\n",
+	} {
+		if !strings.Contains(makefile, expected) {
+			t.Fatalf("Expected make output to contain %q, got:\n%v", expected, makefile)
+		}
+	}
+
+	justfile := renderFormat(t, "just", services)
+	for _, expected := range []string{
+		"up *ARGS: (up-cache ARGS) (up-db ARGS) (up-web ARGS) (up-web-2 ARGS)\n",
+		"up-web *ARGS: (up-cache ARGS) (up-db ARGS)\n",
+		`{{compose}} -f "/srv/web/docker-compose.yml" up -d "web" {{ARGS}}`,
+		"group-api-down *ARGS:\n    @echo \"Stopping web...\"",
+		"    @echo \"db is shared with other groups. Leaving it running...\"\n",
+	} {
+		if !strings.Contains(justfile, expected) {
+			t.Fatalf("Expected just output to contain %q, got:\n%v", expected, justfile)
+		}
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "dockermi.mk")
+	if err := os.WriteFile(scriptPath, []byte(makefile), 0644); err != nil {
+		t.Fatalf("Error writing makefile: %v", err)
+	}
+	read, err := script.ReadScriptServices(scriptPath)
+	if err != nil {
+		t.Fatalf("Error reading script services: %v", err)
+	}
+	if len(read) != 4 || read[0].ServiceName != "Cache" || read[3].ComposeFile != "/srv/web/docker-compose.yml" {
+		t.Fatalf("Expected the make targets in start order, got: %+v", read)
+	}
+
+	if _, err := exec.LookPath("make"); err != nil {
+		return
+	}
+	out, err := exec.Command("make", "-s", "-f", scriptPath, "up-web-2", "COMPOSE=echo").CombinedOutput()
+	if err != nil {
+		t.Fatalf("make failed: %v\n%s", err, out)
+	}
+	expected := "Starting Cache...\n-f /srv/db/docker-compose.yml up -d Cache\nStarting db...\n-f /srv/db/docker-compose.yml up -d db\nStarting web...\n-f /srv/web/docker-compose.yml up -d web\n"
+	if string(out) != expected {
+		t.Fatalf("Expected make output:\n%v\ngot:\n%v", expected, string(out))
+	}
+
+	// The api group starts the shared db only when it is not running and
+	// leaves it running for the admin group
+	out, err = exec.Command("make", "-s", "-f", scriptPath, "group-api-up", "group-api-down", "COMPOSE=echo").CombinedOutput()
+	if err != nil {
+		t.Fatalf("make failed: %v\n%s", err, out)
+	}
+	expected = "Starting db...\n-f /srv/db/docker-compose.yml up -d db\nStarting web...\n-f /srv/web/docker-compose.yml up -d web\n" +
+		"Stopping web...\n-f /srv/web/docker-compose.yml stop web\ndb is shared with other groups. Leaving it running...\n"
+	if string(out) != expected {
+		t.Fatalf("Expected make output:\n%v\ngot:\n%v", expected, string(out))
+	}
+}
+
+// progressRecorder is an Observer recording its calls.
+type progressRecorder struct {
+	scriptPath string
+	calls      []string
+}
+
+func (p *progressRecorder) Start(total int) {
+	p.calls = append(p.calls, "start "+strconv.Itoa(total))
+}
+
+func (p *progressRecorder) Service(service script.Service) {
+	// Services are reported while the script is generated, before it is written
+	if _, err := os.Stat(p.scriptPath); err == nil {
+		service.ServiceName += " (written)"
+	}
+	p.calls = append(p.calls, service.ServiceName)
+}
+
+func (p *progressRecorder) Done() {
+	p.calls = append(p.calls, "done")
+}
+
+func TestCreateScriptProgress(t *testing.T) {
+	services := types.ServiceScriptReturn{
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml"},
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml"},
+	}
+	observer := &progressRecorder{scriptPath: filepath.Join(t.TempDir(), "dockermi.sh")}
+	if err := script.CreateDockermiScript(observer.scriptPath, services, script.Header{Version: "test"}, script.Options{Observer: observer}); err != nil {
+		t.Fatalf("Error creating script: %v", err)
+	}
+	if calls := strings.Join(observer.calls, ", "); calls != "start 2, db, web, done" {
+		t.Fatalf("Expected progress in start order, got: %v", calls)
+	}
+}
+
+func TestCreateScriptKeepsHandWrittenScripts(t *testing.T) {
+	projectDir := t.TempDir()
+	composeFile := filepath.Join(projectDir, "docker-compose.yml")
+	if err := os.WriteFile(composeFile, []byte("services:\n  web:\n    image: nginx\n"), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	services := types.ServiceScriptReturn{{Order: "1", ServiceName: "web", ComposeFile: composeFile}}
+	header, err := script.NewHeader("test", projectDir, "", nil, services)
+	if err != nil {
+		t.Fatalf("Error creating header: %v", err)
+	}
+
+	scriptPath := filepath.Join(projectDir, "dockermi.sh")
+	handWritten := "#!/bin/bash\ndocker-compose up -d web\n"
+	if err := os.WriteFile(scriptPath, []byte(handWritten), 0755); err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+
+	if err := script.CreateDockermiScript(scriptPath, services, header, script.Options{}); err == nil {
+		t.Fatalf("Expected a script without header not to be overwritten")
+	}
+	if content, _ := os.ReadFile(scriptPath); string(content) != handWritten {
+		t.Fatalf("Expected the hand written script to be kept, got:\n%s", content)
+	}
+
+	if err := script.CreateDockermiScript(scriptPath, services, header, script.Options{Overwrite: true}); err != nil {
+		t.Fatalf("Error overwriting script: %v", err)
+	}
+	if backup, _ := os.ReadFile(scriptPath + ".bak"); string(backup) != handWritten {
+		t.Fatalf("Expected the hand written script in the backup, got:\n%s", backup)
+	}
+	generated, err := os.ReadFile(scriptPath)
+	if err != nil || !strings.Contains(string(generated), `up -d "web"`) {
+		t.Fatalf("Expected the generated script, got: %s (%v)", generated, err)
+	}
+
+	// A generated script is replaced without --overwrite and becomes the new backup
+	if err := script.CreateDockermiScript(scriptPath, services, header, script.Options{}); err != nil {
+		t.Fatalf("Error regenerating script: %v", err)
+	}
+	if backup, _ := os.ReadFile(scriptPath + ".bak"); string(backup) != string(generated) {
+		t.Fatalf("Expected the previous script in the backup, got:\n%s", backup)
+	}
+
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		t.Fatalf("Error reading project directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, " ") != "docker-compose.yml dockermi.sh dockermi.sh.bak" {
+		t.Fatalf("Expected no temporary files to be left, got: %v", names)
+	}
+	if info, err := os.Stat(scriptPath); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("Expected the script to be executable, got: %v (%v)", info.Mode(), err)
+	}
+}
+
+// writeFakeTool writes an executable shell script named name into dir.
+func writeFakeTool(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("Error writing fake %s: %v", name, err)
+	}
+}
+
+func TestPodmanScriptRuns(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+
+	// podman-compose has no 'ps --filter', so shared services are looked up
+	// with 'podman ps'. The fake podman reports db as running.
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "compose.log")
+	writeFakeTool(t, binDir, "podman-compose", "echo \"podman-compose $*\" >> \""+logPath+"\"\n")
+	writeFakeTool(t, binDir, "podman", "echo \"podman $*\" >> \""+logPath+"\"\necho 4f2a9c1e\n")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	services := types.ServiceScriptReturn{
+		{Order: "2", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml"},
+		{Order: "1", ServiceName: "db", ComposeFile: "/srv/db/docker-compose.yml", Shared: true},
+	}
+	for _, format := range []string{"bash", "sh"} {
+		content, err := script.Generate(services, script.Header{Version: "test"}, script.Options{Format: format, Compose: "podman-compose"})
+		if err != nil {
+			t.Fatalf("Error generating %s script: %v", format, err)
+		}
+		if strings.Contains(string(content), "docker-compose -f") {
+			t.Fatalf("Expected the %s script to only invoke podman-compose:\n%s", format, content)
+		}
+
+		scriptPath := filepath.Join(t.TempDir(), "dockermi.sh")
+		if err := os.WriteFile(scriptPath, content, 0755); err != nil {
+			t.Fatalf("Error writing script: %v", err)
+		}
+		if _, err := exec.LookPath(format); err != nil {
+			continue
+		}
+		os.Remove(logPath)
+		if out, err := exec.Command(format, scriptPath, "up").CombinedOutput(); err != nil {
+			t.Fatalf("%s up failed: %v\n%s", format, err, out)
+		}
+
+		log, err := os.ReadFile(logPath)
+		if err != nil {
+			t.Fatalf("Error reading compose log: %v", err)
+		}
+		expected := strings.Join([]string{
+			"podman ps --quiet --filter label=com.docker.compose.project=db --filter label=com.docker.compose.service=db --filter status=running",
+			"podman-compose -f /srv/web/docker-compose.yml up -d web",
+		}, "\n") + "\n"
+		if string(log) != expected {
+			t.Fatalf("Expected %s calls:\n%v\ngot:\n%v", format, expected, string(log))
+		}
+	}
+}
+
+func TestScriptEngineTarget(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+
+	// Scripts use the recorded context unless the environment selects an engine
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	envLog := filepath.Join(binDir, "compose.log")
+	fakeCompose := "#!/bin/sh\necho \"context=$DOCKER_CONTEXT host=$DOCKER_HOST\" >> \"" + envLog + "\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte(fakeCompose), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+	services := types.ServiceScriptReturn{{Order: "1", ServiceName: "web", ComposeFile: "/srv/web/docker-compose.yml"}}
+	content, err := script.Generate(services, script.Header{Version: "test"}, script.Options{Format: "sh", Target: compose.Target{Context: "build-box"}})
+	if err != nil {
+		t.Fatalf("Error generating script: %v", err)
+	}
+	scriptPath := filepath.Join(t.TempDir(), "dockermi.sh")
+	if err := os.WriteFile(scriptPath, content, 0755); err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+	for _, env := range []string{"DOCKER_CONTEXT=", "DOCKER_HOST=tcp://other:2375"} {
+		cmd := exec.Command("sh", scriptPath, "up")
+		cmd.Env = append(os.Environ(), env)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("sh up failed: %v\n%s", err, out)
+		}
+	}
+	log, err := os.ReadFile(envLog)
+	if err != nil {
+		t.Fatalf("Error reading compose log: %v", err)
+	}
+	if string(log) != "context=build-box host=\ncontext= host=tcp://other:2375\n" {
+		t.Fatalf("Unexpected compose environment:\n%s", log)
+	}
+}
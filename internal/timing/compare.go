@@ -0,0 +1,136 @@
+package timing
+
+import (
+	"sort"
+	"time"
+)
+
+// MinRegression is the smallest slowdown reported as a regression, so that
+// services taking a few milliseconds are not flagged for noise.
+const MinRegression = 500 * time.Millisecond
+
+// Row kinds.
+const (
+	RowService = "service"
+	RowPhase   = "phase"
+	RowTotal   = "total"
+)
+
+// Row compares the latest duration of a service, a phase or a whole run with
+// the average of the previous runs.
+type Row struct {
+	Kind string
+	// Name is the service name, the phase order or "total".
+	Name        string
+	ComposeFile string
+	Phase       string
+	// Latest is the duration in the latest run. For services, Command and
+	// Healthy split it into the compose command and the wait for health.
+	Latest           time.Duration
+	Command, Healthy time.Duration
+	// Baseline is the average duration in the previous runs and Samples the
+	// number of runs it averages; both are zero when there is no previous run.
+	Baseline time.Duration
+	Samples  int
+	// Regression is true when Latest is slower than Baseline by more than the
+	// threshold given to Compare.
+	Regression bool
+}
+
+// Change returns the relative change of Latest over Baseline (0.25 for 25%
+// slower), or 0 without a baseline.
+func (r Row) Change() float64 {
+	if r.Baseline == 0 {
+		return 0
+	}
+	return float64(r.Latest-r.Baseline) / float64(r.Baseline)
+}
+
+// Successful returns the runs that finished without error.
+func Successful(runs []Run) []Run {
+	var successful []Run
+	for _, run := range runs {
+		if run.Error == "" {
+			successful = append(successful, run)
+		}
+	}
+	return successful
+}
+
+// Compare compares the last of runs, which are oldest first, with the
+// average of the others. Services come first, slowest first, then the phases
+// in start order and the total. A row is a regression when it is slower than
+// its baseline by more than threshold (0.2 for 20%) and by at least MinRegression.
+func Compare(runs []Run, threshold float64) []Row {
+	if len(runs) == 0 {
+		return nil
+	}
+	latest, previous := runs[len(runs)-1], runs[:len(runs)-1]
+
+	var rows []Row
+	for _, service := range latest.Services {
+		row := Row{
+			Kind:        RowService,
+			Name:        service.Service,
+			ComposeFile: service.ComposeFile,
+			Phase:       service.Phase,
+			Latest:      time.Duration(service.Total),
+			Command:     time.Duration(service.Command),
+			Healthy:     time.Duration(service.Healthy),
+		}
+		row.Baseline, row.Samples = average(previous, func(run Run) (time.Duration, bool) {
+			for _, s := range run.Services {
+				if s.ID() == service.ID() {
+					return time.Duration(s.Total), true
+				}
+			}
+			return 0, false
+		})
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Latest > rows[j].Latest
+	})
+
+	for _, phase := range latest.Phases {
+		row := Row{Kind: RowPhase, Name: phase.Order, Phase: phase.Order, Latest: time.Duration(phase.Duration)}
+		row.Baseline, row.Samples = average(previous, func(run Run) (time.Duration, bool) {
+			for _, p := range run.Phases {
+				if p.Order == phase.Order {
+					return time.Duration(p.Duration), true
+				}
+			}
+			return 0, false
+		})
+		rows = append(rows, row)
+	}
+
+	total := Row{Kind: RowTotal, Name: "total", Latest: time.Duration(latest.Duration)}
+	total.Baseline, total.Samples = average(previous, func(run Run) (time.Duration, bool) {
+		return time.Duration(run.Duration), true
+	})
+	rows = append(rows, total)
+
+	for i := range rows {
+		slower := rows[i].Latest - rows[i].Baseline
+		rows[i].Regression = rows[i].Samples > 0 && slower >= MinRegression && rows[i].Change() > threshold
+	}
+	return rows
+}
+
+// average returns the average of the durations value finds in runs, and the
+// number of runs it found one in.
+func average(runs []Run, value func(Run) (time.Duration, bool)) (time.Duration, int) {
+	var sum time.Duration
+	samples := 0
+	for _, run := range runs {
+		if d, ok := value(run); ok {
+			sum += d
+			samples++
+		}
+	}
+	if samples == 0 {
+		return 0, 0
+	}
+	return sum / time.Duration(samples), samples
+}
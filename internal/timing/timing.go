@@ -0,0 +1,222 @@
+// Package timing records how long the services of an up run take, keeps a
+// history of runs and compares the latest run with the previous ones.
+package timing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/events"
+	dockermiUtils "github.com/mkhuda/dockermi/utils"
+)
+
+// KeepRuns is the number of runs kept in a history directory; older runs are
+// removed when a run is saved.
+const KeepRuns = 100
+
+// Dir returns the history directory of the project at root.
+func Dir(root string) string {
+	return filepath.Join(root, ".dockermi", "runs")
+}
+
+// Seconds is a duration stored in JSON as a number of seconds.
+type Seconds time.Duration
+
+// MarshalJSON encodes s in seconds.
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(s).Seconds())
+}
+
+// UnmarshalJSON decodes a number of seconds.
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	*s = Seconds(seconds * float64(time.Second))
+	return nil
+}
+
+// Run is the timing of one run.
+type Run struct {
+	Action string `json:"action"`
+	// Script identifies what was run: the script name, or the group key.
+	Script   string    `json:"script"`
+	Started  time.Time `json:"started"`
+	Duration Seconds   `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+	Phases   []Phase   `json:"phases"`
+	Services []Service `json:"services"`
+}
+
+// Phase is the timing of a dockermi.order phase.
+type Phase struct {
+	Order    string  `json:"order"`
+	Duration Seconds `json:"duration"`
+}
+
+// Service is the timing of a service: the compose command, then the wait
+// until the service was healthy.
+type Service struct {
+	Service     string  `json:"service"`
+	ComposeFile string  `json:"compose_file"`
+	Phase       string  `json:"phase"`
+	Command     Seconds `json:"command"`
+	Healthy     Seconds `json:"healthy"`
+	Total       Seconds `json:"total"`
+	Health      string  `json:"health,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// ID identifies the service across runs.
+func (s Service) ID() string {
+	return s.ComposeFile + "#" + s.Service
+}
+
+// Recorder is an events.Observer building the timing of a run.
+type Recorder struct {
+	Run Run
+}
+
+// NewRecorder returns a Recorder for a run of script.
+func NewRecorder(script string) *Recorder {
+	return &Recorder{Run: Run{Script: script}}
+}
+
+// Event records event.
+func (r *Recorder) Event(event events.Event) {
+	switch event.Type {
+	case events.PlanResolved:
+		r.Run.Action = event.Action
+		r.Run.Started = event.Time
+	case events.ServiceHealthy, events.ServiceFailed:
+		r.Run.Services = append(r.Run.Services, Service{
+			Service:     event.Service,
+			ComposeFile: event.ComposeFile,
+			Phase:       event.Phase,
+			Command:     Seconds(event.Command),
+			Healthy:     Seconds(event.Duration - event.Command),
+			Total:       Seconds(event.Duration),
+			Health:      event.Health,
+			Error:       event.Error,
+		})
+	case events.PhaseComplete:
+		r.Run.Phases = append(r.Run.Phases, Phase{Order: event.Phase, Duration: Seconds(event.Duration)})
+	case events.RunFinished:
+		r.Run.Duration = Seconds(event.Duration)
+		r.Run.Error = event.Error
+	}
+}
+
+// Save writes run into dir, named after its start time, and removes the
+// oldest runs beyond KeepRuns. It returns the path of the written file.
+func Save(dir string, run Run) (string, error) {
+	// Everyone in the group running dockermi in the project records runs
+	for _, shared := range []string{filepath.Dir(dir), dir} {
+		if err := dockermiUtils.MkdirShared(shared); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", run.Started.UTC().Format("20060102T150405.000000000Z"), run.Action))
+	if err := writeRun(path, append(data, '\n')); err != nil {
+		return "", err
+	}
+
+	files, err := runFiles(dir)
+	if err != nil {
+		return path, err
+	}
+	for len(files) > KeepRuns {
+		if err := os.Remove(files[0]); err != nil {
+			return path, err
+		}
+		files = files[1:]
+	}
+	return path, nil
+}
+
+// writeRun writes data to a temporary file in the directory of path and
+// renames it into place, so that Load never reads a partially written run.
+func writeRun(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // no-op once the file has been renamed
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load returns the runs of script in dir, oldest first. Only the last n runs
+// are returned when n is positive. A missing directory holds no runs.
+func Load(dir, script string, n int) ([]Run, error) {
+	files, err := runFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for i := len(files) - 1; i >= 0 && (n <= 0 || len(runs) < n); i-- {
+		data, err := os.ReadFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			return nil, fmt.Errorf("invalid run %s: %w", files[i], err)
+		}
+		if run.Script == script {
+			runs = append(runs, run)
+		}
+	}
+
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs, nil
+}
+
+// runFiles returns the run files of dir, oldest first.
+func runFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
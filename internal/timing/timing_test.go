@@ -0,0 +1,102 @@
+package timing_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/runner"
+	"github.com/mkhuda/dockermi/internal/timing"
+	"github.com/mkhuda/dockermi/types"
+)
+
+func TestTiming(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+	services := []types.ServiceScript{
+		{Order: "1", ServiceName: "db", ComposeFile: "/app/docker-compose.yml"},
+		{Order: "2", ServiceName: "api", ComposeFile: "/app/docker-compose.yml"},
+	}
+
+	recorder := timing.NewRecorder("dockermi.sh")
+	r := runner.Runner{
+		Command:  []string{filepath.Join(binDir, "docker-compose")},
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Observer: recorder,
+		Wait: func(ctx context.Context, service types.ServiceScript) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "healthy", nil
+		},
+	}
+	if err := r.Run(context.Background(), "up", services); err != nil {
+		t.Fatalf("Error running up: %v", err)
+	}
+	run := recorder.Run
+	if run.Action != "up" || len(run.Services) != 2 || len(run.Phases) != 2 || run.Duration == 0 {
+		t.Fatalf("Unexpected run timing: %+v", run)
+	}
+	db := run.Services[0]
+	if db.Service != "db" || db.Phase != "1" || db.Healthy < timing.Seconds(10*time.Millisecond) || db.Total != db.Command+db.Healthy {
+		t.Fatalf("Unexpected db timing: %+v", db)
+	}
+
+	// Runs are kept oldest first, and only the last KeepRuns of them
+	dir := timing.Dir(t.TempDir())
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < timing.KeepRuns+2; i++ {
+		run := timing.Run{Action: "up", Script: "dockermi.sh", Started: started.Add(time.Duration(i) * time.Minute), Duration: timing.Seconds(time.Duration(i) * time.Second)}
+		if _, err := timing.Save(dir, run); err != nil {
+			t.Fatalf("Error saving run: %v", err)
+		}
+	}
+	if _, err := timing.Save(dir, timing.Run{Action: "up", Script: "shop", Started: started}); err != nil {
+		t.Fatalf("Error saving run: %v", err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != timing.KeepRuns {
+		t.Fatalf("Expected %d runs to be kept, got %d", timing.KeepRuns, len(files))
+	}
+	runs, err := timing.Load(dir, "dockermi.sh", 3)
+	if err != nil {
+		t.Fatalf("Error loading runs: %v", err)
+	}
+	if len(runs) != 3 || time.Duration(runs[0].Duration) != time.Duration(timing.KeepRuns-1)*time.Second ||
+		time.Duration(runs[2].Duration) != time.Duration(timing.KeepRuns+1)*time.Second {
+		t.Fatalf("Expected the last 3 runs oldest first, got: %+v", runs)
+	}
+
+	// The latest run is compared with the average of the previous ones
+	second := timing.Seconds(time.Second)
+	history := []timing.Run{
+		{Duration: 4 * second, Phases: []timing.Phase{{Order: "1", Duration: 4 * second}}, Services: []timing.Service{
+			{Service: "db", Phase: "1", Total: 2 * second}, {Service: "api", Phase: "1", Total: 4 * second}}},
+		{Duration: 6 * second, Phases: []timing.Phase{{Order: "1", Duration: 6 * second}}, Services: []timing.Service{
+			{Service: "db", Phase: "1", Total: 4 * second}, {Service: "api", Phase: "1", Total: 6 * second}}},
+		{Duration: 60 * second, Error: "db: exit status 1"},
+		{Duration: 9 * second, Phases: []timing.Phase{{Order: "1", Duration: 9 * second}}, Services: []timing.Service{
+			{Service: "db", Phase: "1", Total: 9 * second}, {Service: "api", Phase: "1", Total: 5 * second}, {Service: "cache", Phase: "1", Total: 8 * second}}},
+	}
+	rows := timing.Compare(timing.Successful(history), 0.2)
+	var summary []string
+	for _, row := range rows {
+		summary = append(summary, fmt.Sprintf("%s:%s:%v:%v", row.Kind, row.Name, row.Baseline, row.Regression))
+	}
+	expected := []string{
+		"service:db:3s:true",
+		"service:cache:0s:false",
+		"service:api:5s:false",
+		"phase:1:5s:true",
+		"total:total:5s:true",
+	}
+	if strings.Join(summary, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected rows:\n%v\ngot:\n%v", expected, summary)
+	}
+}
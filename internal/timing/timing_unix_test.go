@@ -0,0 +1,33 @@
+//go:build !windows
+
+package timing_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/timing"
+)
+
+func TestSavePermissions(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(022))
+
+	dir := timing.Dir(t.TempDir())
+	if _, err := timing.Save(dir, timing.Run{Action: "up", Script: "dockermi.sh", Started: time.Now()}); err != nil {
+		t.Fatalf("Error saving run: %v", err)
+	}
+
+	// Everyone in the group must be able to record runs
+	for _, path := range []string{filepath.Dir(dir), dir} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", path, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0775 {
+			t.Fatalf("Expected %s to be created 0775, got %o", path, perm)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package watch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that may affect a compose file.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// inotify is a notifier watching every directory below root with inotify.
+type inotify struct {
+	fd      int
+	file    *os.File
+	watched map[string]bool
+	paths   map[int32]string // watch descriptor -> directory
+	events  chan struct{}
+}
+
+// newNotifier starts an inotify instance watching the directories below root.
+func newNotifier(root string) (notifier, error) {
+	// A non-blocking descriptor lets the runtime poller wait for events, so
+	// that closing the file also stops the pending read
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &inotify{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watched: make(map[string]bool),
+		paths:   make(map[int32]string),
+		events:  make(chan struct{}, 1),
+	}
+	n.addWatches(root)
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan struct{} {
+	return n.events
+}
+
+func (n *inotify) Close() error {
+	return n.file.Close()
+}
+
+// read turns inotify events into wake-ups until the file is closed.
+func (n *inotify) read() {
+	defer close(n.events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+		// Directories created or moved in need their own watch, as do the
+		// directories below them
+		for _, dir := range n.handle(buf[:size]) {
+			n.addWatches(dir)
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// addWatches watches dir and every directory below it that is not watched yet.
+func (n *inotify) addWatches(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || n.watched[path] {
+			return nil
+		}
+		if wd, err := syscall.InotifyAddWatch(n.fd, path, inotifyMask); err == nil {
+			n.watched[path] = true
+			n.paths[int32(wd)] = path
+		}
+		return nil
+	})
+}
+
+// handle forgets the directories whose watch was removed by the kernel, e.g.
+// because they were deleted, so that they are watched again if recreated. It
+// returns the directories created or moved into a watched directory.
+func (n *inotify) handle(buf []byte) []string {
+	var created []string
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		name := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+		switch {
+		case event.Mask&syscall.IN_IGNORED != 0:
+			delete(n.watched, n.paths[event.Wd])
+			delete(n.paths, event.Wd)
+		case event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			if dir, ok := n.paths[event.Wd]; ok {
+				created = append(created, filepath.Join(dir, string(bytes.TrimRight(name, "\x00"))))
+			}
+		}
+		offset += syscall.SizeofInotifyEvent + int(event.Len)
+	}
+	return created
+}
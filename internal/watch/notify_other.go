@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+// newNotifier is not available on this platform; Watch relies on scanning.
+func newNotifier(root string) (notifier, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
@@ -0,0 +1,159 @@
+// Package watch reports changes to the compose files below a directory.
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default timings used when Options leaves them zero.
+const (
+	DefaultInterval = time.Second
+	DefaultDebounce = 500 * time.Millisecond
+)
+
+// Options controls how a tree is watched.
+type Options struct {
+	// Interval is how often the tree is scanned for changes. Where file system
+	// notifications are available (inotify on Linux) changes are noticed
+	// sooner; the scan still catches anything a notification missed.
+	Interval time.Duration
+	// Debounce is how long the tree must stay unchanged before the changes
+	// are reported, so that a burst of saves is reported once.
+	Debounce time.Duration
+}
+
+// Change lists the compose files created, modified or removed since the
+// previous report, each in sorted order.
+type Change struct {
+	Created  []string
+	Modified []string
+	Removed  []string
+}
+
+// Empty reports whether no file changed.
+func (c Change) Empty() bool {
+	return len(c.Created) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0
+}
+
+// notifier wakes the watch loop when something below the root may have changed.
+type notifier interface {
+	Events() <-chan struct{}
+	Close() error
+}
+
+// fileState is what a scan records about a compose file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watch calls fn with the changes to the .yml files below root until ctx is
+// cancelled. Files created after Watch started are picked up as well.
+func Watch(ctx context.Context, root string, opts Options, fn func(Change)) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+
+	reported, err := scan(root)
+	if err != nil {
+		return err
+	}
+	last := reported
+
+	var wake <-chan struct{}
+	if n, err := newNotifier(root); err == nil {
+		defer n.Close()
+		wake = n.Events()
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-wake:
+			if !ok {
+				// Notifications stopped; keep going with the periodic scan
+				wake = nil
+				continue
+			}
+		case <-ticker.C:
+		case <-debounce:
+			debounce = nil
+			current, err := scan(root)
+			if err != nil {
+				return err
+			}
+			change := compare(reported, current)
+			reported, last = current, current
+			if !change.Empty() {
+				fn(change)
+			}
+			continue
+		}
+
+		current, err := scan(root)
+		if err != nil {
+			return err
+		}
+		if !compare(last, current).Empty() {
+			// Restart the quiet period on every change
+			debounce = time.After(opts.Debounce)
+		}
+		last = current
+	}
+}
+
+// scan records the size and modification time of every .yml file below root.
+func scan(root string) (map[string]fileState, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileState)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		// Files may disappear while the tree is walked; skip what cannot be read
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".yml") {
+			files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return files, nil
+}
+
+// compare returns the files created, modified or removed between two scans.
+func compare(old, new map[string]fileState) Change {
+	var change Change
+	for path, state := range new {
+		previous, ok := old[path]
+		switch {
+		case !ok:
+			change.Created = append(change.Created, path)
+		case previous.size != state.size || !previous.modTime.Equal(state.modTime):
+			change.Modified = append(change.Modified, path)
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			change.Removed = append(change.Removed, path)
+		}
+	}
+	sort.Strings(change.Created)
+	sort.Strings(change.Modified)
+	sort.Strings(change.Removed)
+	return change
+}
@@ -0,0 +1,56 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/watch"
+)
+
+func TestWatchMovedDirectory(t *testing.T) {
+	projectDir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan watch.Change, 10)
+	done := make(chan error, 1)
+	go func() {
+		// Only inotify wakes the watcher within the test
+		done <- watch.Watch(ctx, projectDir, watch.Options{Interval: time.Hour, Debounce: 50 * time.Millisecond}, func(change watch.Change) {
+			changes <- change
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// A tree moved in is watched down to its deepest directory
+	staging := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(staging, "services", "db"), 0755); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	if err := os.Rename(filepath.Join(staging, "services"), filepath.Join(projectDir, "services")); err != nil {
+		t.Fatalf("Error moving directory: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	composeFile := filepath.Join(projectDir, "services", "db", "docker-compose.yml")
+	if err := os.WriteFile(composeFile, []byte("services:\n  db:\n    image: postgres\n"), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+	select {
+	case change := <-changes:
+		if strings.Join(change.Created, ",") != composeFile {
+			t.Fatalf("Expected %s created, got: %+v", composeFile, change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the compose file to be reported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected watch to stop without error, got: %v", err)
+	}
+}
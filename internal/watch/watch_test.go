@@ -0,0 +1,79 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkhuda/dockermi/internal/watch"
+)
+
+func TestWatch(t *testing.T) {
+	projectDir := t.TempDir()
+	composeFile := filepath.Join(projectDir, "web", "docker-compose.yml")
+	if err := os.MkdirAll(filepath.Dir(composeFile), 0755); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	if err := os.WriteFile(composeFile, []byte("services:\n  web:\n    image: nginx\n"), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan watch.Change, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.Watch(ctx, projectDir, watch.Options{Interval: 20 * time.Millisecond, Debounce: 100 * time.Millisecond}, func(change watch.Change) {
+			changes <- change
+		})
+	}()
+	// Let the watcher take its first snapshot
+	time.Sleep(100 * time.Millisecond)
+
+	// A burst of writes to several files is reported as a single change
+	newFile := filepath.Join(projectDir, "db", "docker-compose.yml")
+	if err := os.MkdirAll(filepath.Dir(newFile), 0755); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	for _, image := range []string{"httpd", "caddy", "traefik"} {
+		if err := os.WriteFile(composeFile, []byte("services:\n  web:\n    image: "+image+"\n"), 0644); err != nil {
+			t.Fatalf("Error updating compose file: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.WriteFile(newFile, []byte("services:\n  db:\n    image: postgres\n"), 0644); err != nil {
+		t.Fatalf("Error writing compose file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+
+	select {
+	case change := <-changes:
+		if strings.Join(change.Created, ",") != newFile || strings.Join(change.Modified, ",") != composeFile || len(change.Removed) != 0 {
+			t.Fatalf("Expected %s created and %s modified, got: %+v", newFile, composeFile, change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a change to be reported")
+	}
+
+	if err := os.Remove(composeFile); err != nil {
+		t.Fatalf("Error removing compose file: %v", err)
+	}
+	select {
+	case change := <-changes:
+		if strings.Join(change.Removed, ",") != composeFile || len(change.Created)+len(change.Modified) != 0 {
+			t.Fatalf("Expected %s removed, got: %+v", composeFile, change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a removal to be reported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected watch to stop without error, got: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package dockermi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// popFlag removes every occurrence of the boolean option --name from args and
+// reports whether it was present. The remaining args are passed through to
+// docker-compose untouched.
+func popFlag(args []string, name string) (bool, []string) {
+	found := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--"+name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return found, rest
+}
+
+// popFlagValue removes the option --name (either "--name value" or
+// "--name=value") from args and returns its value.
+func popFlagValue(args []string, name string) (string, []string, error) {
+	value := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--"+name:
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("missing value for --%s", name)
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--"+name+"="):
+			value = strings.TrimPrefix(arg, "--"+name+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest, nil
+}
@@ -0,0 +1,133 @@
+package dockermi_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/script"
+	dockermi "github.com/mkhuda/dockermi/pkg"
+)
+
+// benchComposeFiles is the number of compose files in the synthetic project tree.
+const benchComposeFiles = 300
+
+// syntheticProject writes a tree of compose files with two labelled services each.
+func syntheticProject(b *testing.B) string {
+	b.Helper()
+	projectDir := b.TempDir()
+	for i := 0; i < benchComposeFiles; i++ {
+		dir := filepath.Join(projectDir, fmt.Sprintf("group%02d", i%20), fmt.Sprintf("app%03d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatalf("Error creating directory: %v", err)
+		}
+		compose := fmt.Sprintf(`services:
+  web%[1]d:
+    image: nginx
+    ports:
+      - "%[2]d:80"
+    depends_on:
+      - db%[1]d
+    labels:
+      dockermi.order: "%[3]d"
+      dockermi.active: "true"
+      dockermi.key: "group%[4]02d"
+  db%[1]d:
+    image: postgres
+    environment:
+      POSTGRES_DB: app%[1]d
+    labels:
+      dockermi.order: "%[5]d"
+      dockermi.active: "true"
+`, i, 8000+i, i%10+1, i%20, i%10)
+		if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+			b.Fatalf("Error writing compose file: %v", err)
+		}
+	}
+	return projectDir
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	projectDir := syntheticProject(b)
+
+	services, err := dockercompose.FindServices(projectDir, false)
+	if err != nil {
+		b.Fatalf("Error finding services: %v", err)
+	}
+	header, err := script.NewHeader(dockermi.GetVersion(), projectDir, "", nil, services)
+	if err != nil {
+		b.Fatalf("Error creating header: %v", err)
+	}
+
+	b.Run("FindServices", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := dockercompose.FindServices(projectDir, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Generate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := script.Generate(services, header, script.Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("CreateDockermiScript", func(b *testing.B) {
+		scriptPath := filepath.Join(b.TempDir(), "dockermi.sh")
+		for i := 0; i < b.N; i++ {
+			if err := script.CreateDockermiScript(scriptPath, services, header, script.Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// walkSequential parses the compose files under root one after another during
+// a filepath.Walk, the way FindServices did before Discover. It is the baseline
+// the worker pool is measured against.
+func walkSequential(root string) (int, error) {
+	parsed := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yml") {
+			return nil
+		}
+		if _, err := dockercompose.ParseComposeFile(path, false, false); err != nil {
+			return err
+		}
+		parsed++
+		return nil
+	})
+	return parsed, err
+}
+
+func BenchmarkDiscover(b *testing.B) {
+	projectDir := syntheticProject(b)
+
+	b.Run("Walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := walkSequential(projectDir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("Workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := dockercompose.Discover(context.Background(), projectDir, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
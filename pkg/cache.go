@@ -0,0 +1,27 @@
+package dockermi
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/cache"
+)
+
+// handleCacheCommand handles 'dockermi cache <subcommand>' for the parse cache.
+func handleCacheCommand(args []string) (string, error) {
+	if len(args) == 0 || args[0] != "clear" {
+		return "", fmt.Errorf("usage: dockermi cache clear")
+	}
+
+	parseCache, err := cache.Open()
+	if err != nil {
+		return "", err
+	}
+	removed, err := parseCache.Clear()
+	if err != nil {
+		return "", err
+	}
+
+	color.Green("Removed %d cached compose file(s) from %s", removed, parseCache.Dir())
+	return parseCache.Dir(), nil
+}
@@ -0,0 +1,122 @@
+package dockermi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/registry"
+	"github.com/mkhuda/dockermi/internal/script"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// checkScripts compares the dockermi scripts in projectDir and the group scripts
+// created from projectDir against what would be generated now. It returns an
+// error when any of them drifted, so the command exits non-zero.
+func checkScripts(projectDir string, force bool) (string, error) {
+	drifted, checked := 0, 0
+
+	scriptPath := ""
+	for _, ext := range script.Extensions() {
+		path := filepath.Join(projectDir, "dockermi"+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		scriptPath = path
+
+		// Compare with the flags the script was generated with
+		scriptForce := force
+		if header, found, err := script.ReadHeader(path); err == nil && found {
+			scriptForce = force || header.HasFlag("--force")
+		}
+		services, err := dockercompose.FindServices(projectDir, scriptForce)
+		if err != nil {
+			return "", err
+		}
+		changed, err := checkScript(path, services)
+		if err != nil {
+			return "", err
+		}
+		checked++
+		if changed {
+			drifted++
+		}
+	}
+
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+
+	var groups map[string][]DockermiTypes.ServiceScript
+	for _, e := range reg.List() {
+		if e.Root != projectDir {
+			continue
+		}
+		if groups == nil {
+			if groups, err = dockercompose.FindServicesWithKey(projectDir); err != nil {
+				return "", err
+			}
+		}
+		changed, err := checkScript(e.ScriptPath, groups[e.Key])
+		if err != nil {
+			return "", err
+		}
+		checked++
+		if changed {
+			drifted++
+		}
+	}
+
+	if checked == 0 {
+		color.Yellow("No dockermi scripts found for this folder")
+		return "", nil
+	}
+	if drifted > 0 {
+		return "", fmt.Errorf("%d of %d script(s) are out of date", drifted, checked)
+	}
+
+	color.Green("All %d script(s) are up to date", checked)
+	return scriptPath, nil
+}
+
+// checkScript reports the plan differences between an existing script and services.
+func checkScript(scriptPath string, services []DockermiTypes.ServiceScript) (bool, error) {
+	existing, err := script.ReadScriptServices(scriptPath)
+	if os.IsNotExist(err) {
+		color.Red("%s: missing", scriptPath)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	current := append([]DockermiTypes.ServiceScript(nil), services...)
+	plan.Sort(current)
+
+	diff := plan.Compare(existing, current)
+	if diff.Empty() {
+		color.Green("%s: up to date", scriptPath)
+		return false, nil
+	}
+
+	color.Red("%s: out of date", scriptPath)
+	printPlanDiff(diff)
+	return true, nil
+}
+
+// printPlanDiff prints the added, removed and reordered services of a plan diff.
+func printPlanDiff(diff plan.Diff) {
+	for _, service := range diff.Added {
+		color.Green("  + %s (%s)", service.ServiceName, service.ComposeFile)
+	}
+	for _, service := range diff.Removed {
+		color.Red("  - %s (%s)", service.ServiceName, service.ComposeFile)
+	}
+	for _, service := range diff.Reordered {
+		color.Yellow("  ~ %s (%s) moved", service.ServiceName, service.ComposeFile)
+	}
+}
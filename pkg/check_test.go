@@ -0,0 +1,106 @@
+package dockermi_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dockermi "github.com/mkhuda/dockermi/pkg"
+)
+
+// TestMain runs dockermi itself when the test binary is started by
+// runDockermi, as RunDockermi parses the command line once per process.
+func TestMain(m *testing.M) {
+	if os.Getenv("DOCKERMI_TEST_MAIN") == "1" {
+		projectDir, err := os.Getwd()
+		if err == nil {
+			_, err = dockermi.RunDockermi(projectDir)
+		}
+		if err != nil {
+			os.Stderr.WriteString("Error: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// dockermiCommand returns the command running dockermi with args in
+// projectDir, with a home directory of its own for the registry and caches.
+func dockermiCommand(t *testing.T, projectDir string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = projectDir
+	cmd.Env = append(os.Environ(), "DOCKERMI_TEST_MAIN=1", "HOME="+filepath.Join(projectDir, ".home"), "DOCKERMI_COMPOSE=")
+	return cmd
+}
+
+// runDockermi runs dockermi with args in projectDir and returns its output.
+func runDockermi(t *testing.T, projectDir string, args ...string) (string, error) {
+	t.Helper()
+	out, err := dockermiCommand(t, projectDir, args...).CombinedOutput()
+	return string(out), err
+}
+
+// writeCompose writes a compose file defining services at dir/name.
+func writeCompose(t *testing.T, dir, name, services string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatalf("Error creating %s: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name, "docker-compose.yml"), []byte("services:\n"+services), 0644); err != nil {
+		t.Fatalf("Error writing the %s compose file: %v", name, err)
+	}
+}
+
+// service returns the compose definition of an active service with order.
+func service(name, order string) string {
+	return "  " + name + ":\n    image: busybox\n    labels:\n      dockermi.order: \"" + order + "\"\n      dockermi.active: \"true\"\n"
+}
+
+func TestCheckAfterGenerate(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+	writeCompose(t, projectDir, "app", service("api", "2")+service("web", "2"))
+
+	if out, err := runDockermi(t, projectDir); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+	// A script that was just generated is up to date
+	if out, err := runDockermi(t, projectDir, "check"); err != nil || !strings.Contains(out, "up to date") {
+		t.Fatalf("Expected the script to be up to date, got: %v\n%s", err, out)
+	}
+
+	// Changing the dockermi.order of a service does not move it here, but
+	// changes its phase
+	writeCompose(t, projectDir, "app", service("api", "2")+service("web", "3"))
+	out, err := runDockermi(t, projectDir, "check")
+	if err == nil || !strings.Contains(out, "~ web") || strings.Contains(out, "~ api") {
+		t.Fatalf("Expected only web to have moved, got: %v\n%s", err, out)
+	}
+}
+
+func TestComposeAutoPodmanShim(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+
+	// The docker command is the podman-docker shim
+	binDir := t.TempDir()
+	shim := "#!/bin/sh\n[ \"$1\" = \"--version\" ] && echo \"podman version 4.9.3\"\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(shim), 0755); err != nil {
+		t.Fatalf("Error writing fake docker: %v", err)
+	}
+	cmd := dockermiCommand(t, projectDir)
+	cmd.Env = append(cmd.Env, "PATH="+binDir, "DOCKERMI_COMPOSE=auto")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+
+	// The script keeps running Podman once the shim is no longer detected
+	out, err := runDockermi(t, projectDir, "up", "--dry-run", "--context", "remote")
+	if err != nil || !strings.Contains(out, "CONTAINER_CONNECTION=remote docker compose -f") {
+		t.Fatalf("Expected the podman-docker tool to be read back from the script, got: %v\n%s", err, out)
+	}
+}
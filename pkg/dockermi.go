@@ -5,12 +5,22 @@ package dockermi
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mkhuda/dockermi/internal/cache"
+	"github.com/mkhuda/dockermi/internal/compose"
 	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/groupkey"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/registry"
 	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/internal/watch"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
 	dockermiUtils "github.com/mkhuda/dockermi/utils" // Import the utils package
 
 	"github.com/fatih/color"
@@ -36,6 +46,14 @@ func RunDockermi(projectDir string) (string, error) {
 	versionFlag := flag.Bool("version", false, "Display version information")
 	shortVersionFlag := flag.Bool("v", false, "Display version information")
 	force := flag.Bool("force", false, "Force script generation")
+	templatePath := flag.String("template", "", "Path to a custom script template")
+	format := flag.String("format", "", "Script format (bash, powershell)")
+	dryRun := flag.Bool("dry-run", false, "Print what would be done without doing it")
+	overwrite := flag.Bool("overwrite", false, "Replace scripts that have no dockermi header")
+	noCache := flag.Bool("no-cache", false, "Parse every compose file instead of using the parse cache")
+	composeName := flag.String("compose", "", "Compose tool the scripts invoke (docker-compose, docker, podman-docker, podman-compose, podman, or auto to detect it)")
+	contextName := flag.String("context", "", "Docker context the scripts and engine calls use")
+	host := flag.String("host", "", "Engine host the scripts and engine calls use (unix://, tcp:// or ssh://)")
 	flag.Parse()
 
 	// Check for version flags
@@ -49,53 +67,184 @@ func RunDockermi(projectDir string) (string, error) {
 		return "", nil
 	}
 
+	noCacheArg, args := popFlag(flag.Args(), "no-cache")
+	if !*noCache && !noCacheArg {
+		// The cache only speeds things up, so dockermi works without it
+		if parseCache, err := cache.Open(); err == nil {
+			dockercompose.Cache = parseCache
+		}
+	}
+
+	// graph has its own --format values (dot, mermaid, json), so it does not
+	// go through the script generation options
+	if len(args) > 0 && args[0] == "graph" {
+		return handleGraphCommand(projectDir, args[1:], *force, *format)
+	}
+
+	opts, args, err := parseGenerateOptions(args, generateOptions{Force: *force, Template: *templatePath, Format: *format, DryRun: *dryRun, Overwrite: *overwrite, Compose: *composeName,
+		Target: compose.Target{Context: *contextName, Host: *host}})
+	if err != nil {
+		return "", err
+	}
+
 	// Check if the command is provided
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if len(args) > 0 {
+		switch args[0] {
 		case "up":
-			return handleUpDownCommand(projectDir, "up", os.Args[2:])
+			return handleUpDownCommand(projectDir, "up", args[1:], opts)
 		case "down":
-			return handleUpDownCommand(projectDir, "down", os.Args[2:])
+			return handleUpDownCommand(projectDir, "down", args[1:], opts)
 		case "stop":
-			return handleUpDownCommand(projectDir, "down", os.Args[2:])
+			return handleUpDownCommand(projectDir, "down", args[1:], opts)
+		case "restart":
+			return handleUpDownCommand(projectDir, "restart", args[1:], opts)
 		case "create":
-			if len(os.Args) < 3 {
+			all, args := popFlag(args[1:], "all")
+			if all {
+				return createAllDockermiScripts(projectDir, opts)
+			}
+			if len(args) < 1 {
 				return "", fmt.Errorf("missing key for create command")
 			}
-			return createDockermiScript(projectDir, os.Args[2])
+			return createDockermiScript(projectDir, args[0], opts)
+		case "check":
+			return checkScripts(projectDir, opts.Force)
+		case "status":
+			return handleStatusCommand(projectDir, args[1:], opts.Target)
+		case "history":
+			return handleHistoryCommand(projectDir, args[1:])
+		case "stats":
+			return handleStatsCommand(projectDir, args[1:])
+		case "groups":
+			return handleGroupsCommand(args[1:], opts.Target)
+		case "cache":
+			return handleCacheCommand(args[1:])
+		case "watch":
+			return handleWatchCommand(projectDir, args[1:], opts)
+		case "export":
+			return handleExportCommand(projectDir, args[1:], opts)
 		default:
-			return generateScripts(projectDir, *force)
+			return generateScripts(projectDir, opts)
 		}
 	}
 	// If no specific command is provided, generate the scripts
-	return generateScripts(projectDir, *force)
+	return generateScripts(projectDir, opts)
 }
 
-// handleUpDownCommand handles the 'up' command logic.
-func handleUpDownCommand(projectDir string, command string, args []string) (string, error) {
-	color.Green("Executing %v command...", command)
+// handleUpDownCommand handles the 'up', 'down' and 'restart' commands. When
+// --group <key> is given, the group script from ~/.dockermi is used instead of
+// ./dockermi.sh. With opts.DryRun the compose commands are printed instead of
+// running the script. opts.Target selects the engine (see engineTarget).
+// With --events or --junit the services are run phase by phase by dockermi
+// itself, reporting structured events (see runServices).
+// Runs hold the lock of the project or group (see lockRun), and are recorded
+// in the audit log of the project (see auditRun).
+// 'up --watch' keeps running after the start and recreates changed services.
+func handleUpDownCommand(projectDir string, command string, args []string, opts generateOptions) (string, error) {
+	group, args, err := popFlagValue(args, "group")
+	if err != nil {
+		return "", err
+	}
+
+	runOpts, args, err := parseRunOptions(args)
+	if err != nil {
+		return "", err
+	}
+	lockOpts, args, err := parseLockOptions(args)
+	if err != nil {
+		return "", err
+	}
+
+	// restart stops, then starts the services under the same lock
+	actions := []string{command}
+	if command == "restart" {
+		actions = []string{"down", "up"}
+	}
+
+	hotReload := false
+	var watchOpts watch.Options
+	if command == "up" || command == "restart" {
+		if hotReload, args = popFlag(args, "watch"); hotReload {
+			if watchOpts, args, err = parseWatchOptions(args); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	scriptPath := projectScriptPath(projectDir)
+	if group != "" {
+		if scriptPath, err = groupScriptPath(group); err != nil {
+			return "", err
+		}
+	}
+
+	target, err := engineTarget(scriptPath, group, opts.Target)
+	if err != nil {
+		return "", err
+	}
+	env := target.Env(scriptCompose(scriptPath))
 
-	return runDockermiScript(projectDir, command, args)
+	if opts.DryRun {
+		for _, action := range actions {
+			if _, err := printScriptCommands(scriptPath, action, args, env); err != nil {
+				return scriptPath, err
+			}
+		}
+	} else {
+		err = lockRun(projectDir, scriptPath, group, command, lockOpts, func() (err error) {
+			defer func() { err = writeJUnit(runOpts, err) }()
+			for _, action := range actions {
+				if err := auditRun(projectDir, scriptPath, group, action, args, func() error {
+					if !runOpts.enabled() {
+						color.Green("Executing %v command...", action)
+					}
+					if !target.Empty() {
+						color.Green("Using %s", target)
+					}
+					if runOpts.enabled() {
+						return runServices(projectDir, scriptPath, action, args, env, target, runOpts)
+					}
+					return runScript(projectDir, scriptPath, action, args, env)
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil || !hotReload {
+		return scriptPath, err
+	}
+
+	// The lock is released once the services are up: watching runs until
+	// interrupted and must not keep others from stopping the services
+	return scriptPath, hotReloadServices(projectDir, scriptPath, args, opts.DryRun, env, watchOpts)
 }
 
 // generateScripts finds docker-compose.yml files and generates corresponding scripts.
-func generateScripts(projectDir string, force bool) (string, error) {
-	services, err := dockercompose.FindServices(projectDir, force)
-	servicesLength := len(services)
+func generateScripts(projectDir string, opts generateOptions) (string, error) {
+	services, err := dockercompose.FindServices(projectDir, opts.Force)
+	if err != nil {
+		return "", err
+	}
 
-	if servicesLength == 0 {
+	if len(services) == 0 {
 		color.Yellow("No docker-compose.yml found within this folder")
 		return "No docker-compose.yml found within this folder", nil
 	}
 
+	header, err := newHeader(projectDir, "", services, opts)
 	if err != nil {
 		return "", err
 	}
 
 	// Create the dockermi.sh script
-	scriptPath := filepath.Join(projectDir, "dockermi.sh")
-	if err := script.CreateDockermiScript(scriptPath, services); err != nil {
-		color.Red("Error creating dockermi.sh file: %v", err)
+	scriptPath := filepath.Join(projectDir, "dockermi"+opts.extension())
+	if opts.DryRun {
+		return scriptPath, previewScript(scriptPath, services, header, opts)
+	}
+	if err := script.CreateDockermiScript(scriptPath, services, header, opts.scriptOptions()); err != nil {
+		color.Red("Error creating %s file: %v", filepath.Base(scriptPath), err)
 		return "", err
 	}
 
@@ -107,38 +256,96 @@ func generateScripts(projectDir string, force bool) (string, error) {
 	return scriptPath, nil
 }
 
-// runDockermiScript executes the dockermi.sh script located in the current directory
-// with the specified subcommand (e.g., "up" or "down").
-func runDockermiScript(currentDir, subcommand string, options []string) (string, error) {
-	scriptPath := filepath.Join(currentDir, "dockermi.sh")
+// runDockermiScript executes the given dockermi script with the specified
+// subcommand (e.g., "up" or "down"). env is added to the environment of the script.
+func runDockermiScript(scriptPath, subcommand string, options []string, env []string, stdout io.Writer) (string, error) {
 	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("dockermi.sh script not found in current directory")
+		return "", fmt.Errorf("%s script not found", scriptPath)
+	}
+
+	if err := checkScriptSources(scriptPath); err != nil {
+		return "", err
 	}
 
 	color.Green("Running script: %s with subcommand: %s and options: %v", scriptPath, subcommand, options)
 
 	// Prepare command with subcommand and options
-	cmd := exec.Command("bash", append([]string{scriptPath, subcommand}, options...)...) // Pass the subcommand and options to the script
-	cmd.Stdout = os.Stdout
+	cmd := scriptCommand(scriptPath, append([]string{subcommand}, options...)) // Pass the subcommand and options to the script
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 	err := cmd.Run()
 	if err != nil {
-		return "", fmt.Errorf("failed to run dockermi.sh: %w", err)
+		return "", fmt.Errorf("failed to run %s: %w", filepath.Base(scriptPath), err)
 	}
 
 	return scriptPath, nil
 }
 
-// createDockermiScript creates a dockermi-{key}.sh script in the user's home directory.
-func createDockermiScript(projectDir string, key string) (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+// checkScriptSources warns when compose files changed since scriptPath was generated.
+// On an interactive terminal the user is offered to regenerate the script first.
+func checkScriptSources(scriptPath string) error {
+	header, found, err := script.ReadHeader(scriptPath)
+	if err != nil || !found {
+		return err
+	}
+
+	changed := header.ChangedSources()
+	var added []string
+	if len(header.Files) > 0 {
+		current, err := dockercompose.ComposeFiles(header.Root)
+		if err != nil {
+			return err
+		}
+		added = header.AddedFiles(current)
+	}
+	if len(changed) == 0 && len(added) == 0 {
+		return nil
+	}
+
+	color.Yellow("Warning: %s was generated on %s and these compose files changed since:", filepath.Base(scriptPath), header.Generated.Local().Format(time.RFC1123))
+	for _, path := range changed {
+		color.Yellow("  %s", path)
+	}
+	for _, path := range added {
+		color.Yellow("  %s (new)", path)
+	}
+
+	if !isTerminal(os.Stdin) || !confirm("Regenerate the script before running? [y/N] ") {
+		return nil
 	}
 
-	// Ensure the .dockermi directory exists
-	dockermiDir := filepath.Join(homeDir, ".dockermi")
-	err = os.MkdirAll(dockermiDir, os.ModePerm)
+	if header.Key != "" {
+		_, err = createDockermiScript(header.Root, header.Key, optionsFromHeader(header))
+	} else {
+		_, err = generateScripts(header.Root, optionsFromHeader(header))
+	}
+	return err
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question on stdin.
+func confirm(question string) bool {
+	fmt.Print(question)
+	var answer string
+	fmt.Scanln(&answer)
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// createDockermiScript creates a dockermi-{key}.sh script in the user's home directory
+// and records it in the group registry.
+func createDockermiScript(projectDir string, key string, opts generateOptions) (string, error) {
+	if err := groupkey.Validate(key); err != nil {
+		return "", err
+	}
+	reg, err := registry.Load()
 	if err != nil {
 		return "", err
 	}
@@ -155,13 +362,118 @@ func createDockermiScript(projectDir string, key string) (string, error) {
 		return "", fmt.Errorf("no services found for key: %s", key)
 	}
 
-	// Create the dockermi-{key}.sh script
-	scriptPath := filepath.Join(dockermiDir, fmt.Sprintf("dockermi-%s.sh", key))
-	if err := script.CreateDockermiScript(scriptPath, groupedServices); err != nil {
-		color.Red("Error creating dockermi-%s.sh file: %v", key, err)
+	scriptPath, err := writeGroupScript(reg, projectDir, key, groupedServices, opts)
+	if err != nil || opts.DryRun {
+		return scriptPath, err
+	}
+	if err := reg.Save(); err != nil {
 		return "", err
 	}
 
+	color.Blue("You can now run [dockermi up --group %s] from any directory", key)
+	return scriptPath, nil
+}
+
+// createAllDockermiScripts regenerates the group script of every key found in projectDir.
+func createAllDockermiScripts(projectDir string, opts generateOptions) (string, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+
+	services, err := dockercompose.FindServicesWithKey(projectDir)
+	if err != nil {
+		return "", err
+	}
+	if len(services) == 0 {
+		color.Yellow("No services with a dockermi.key label found within this folder")
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(services))
+	for key := range services {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := writeGroupScript(reg, projectDir, key, services[key], opts); err != nil {
+			return "", err
+		}
+	}
+	if opts.DryRun {
+		return "", nil
+	}
+	if err := reg.Save(); err != nil {
+		return "", err
+	}
+
+	color.Blue("Regenerated %d group script(s): %s", len(keys), strings.Join(keys, ", "))
+	return reg.Groups[keys[0]].ScriptPath, nil
+}
+
+// newHeader builds the header of a script generated from projectDir for
+// services, recording the compose files found there so that a compose file
+// added later marks the script as out of date.
+func newHeader(projectDir, key string, services DockermiTypes.ServiceScriptReturn, opts generateOptions) (script.Header, error) {
+	header, err := script.NewHeader(GetVersion(), projectDir, key, opts.flags(), services)
+	if err != nil {
+		return script.Header{}, err
+	}
+	header.Files, err = dockercompose.ComposeFiles(projectDir)
+	return header, err
+}
+
+// sharedIDs returns the plan IDs of the services shared with other groups.
+func sharedIDs(services DockermiTypes.ServiceScriptReturn) []string {
+	var ids []string
+	for _, service := range services {
+		if service.Shared {
+			ids = append(ids, plan.ID(service))
+		}
+	}
+	return ids
+}
+
+// writeGroupScript creates the dockermi-{key}.sh script for the given services and
+// updates the registry entry. The caller is responsible for saving the registry.
+// Without --context or --host the group keeps its default target.
+// With opts.DryRun only the changes to the script are shown.
+func writeGroupScript(reg *registry.Registry, projectDir, key string, services DockermiTypes.ServiceScriptReturn, opts generateOptions) (string, error) {
+	existing, found := reg.Get(key)
+	if found && opts.Target.Empty() {
+		opts.Target = entryTarget(existing)
+	}
+
+	scriptPath, err := registry.ScriptPath(key, opts.extension())
+	if err != nil {
+		return "", err
+	}
+	header, err := newHeader(projectDir, key, services, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.DryRun {
+		return scriptPath, previewScript(scriptPath, services, header, opts)
+	}
+	if err := script.CreateDockermiScript(scriptPath, services, header, opts.scriptOptions()); err != nil {
+		color.Red("Error creating %s file: %v", filepath.Base(scriptPath), err)
+		return "", err
+	}
+
+	reg.Put(registry.Entry{
+		Key:          key,
+		Root:         projectDir,
+		ScriptPath:   scriptPath,
+		CreatedAt:    time.Now(),
+		ServiceCount: len(services),
+		Version:      GetVersion(),
+		Context:      opts.Target.Context,
+		Host:         opts.Target.Host,
+		Shared:       sharedIDs(services),
+	})
+
+	fmt.Println()
 	color.Green("Generated script: %s", scriptPath)
 	return scriptPath, nil
 }
@@ -0,0 +1,93 @@
+package dockermi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/script"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// previewScript prints the diff between the script at scriptPath and the one
+// that would be generated for services, without writing anything.
+func previewScript(scriptPath string, services DockermiTypes.ServiceScriptReturn, header script.Header, opts generateOptions) error {
+	// Keep the generation time of the existing script so it does not show up as a change
+	if existing, found, err := script.ReadHeader(scriptPath); err == nil && found {
+		header.Generated = existing.Generated
+	}
+
+	content, err := script.Generate(services, header, opts.scriptOptions())
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(scriptPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	oldName := scriptPath
+	if existing == nil {
+		oldName = "/dev/null"
+	} else if _, found, _ := script.ReadHeader(scriptPath); !found && !opts.Overwrite {
+		color.Yellow("Dry run: %s has no dockermi header and would not be replaced without --overwrite", scriptPath)
+	}
+	diff := script.Diff(oldName, scriptPath, existing, content)
+	if diff == "" {
+		color.Green("Dry run: %s is up to date", scriptPath)
+		return nil
+	}
+
+	color.Yellow("Dry run: %s would be written with these changes:", scriptPath)
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case len(line) > 0 && line[0] == '+':
+			color.Green("%s", line)
+		case len(line) > 0 && line[0] == '-':
+			color.Red("%s", line)
+		case len(line) > 1 && line[:2] == "@@":
+			color.Cyan("%s", line)
+		default:
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// printScriptCommands prints the compose commands scriptPath runs for command
+// ("up" or "down") with the passthrough args and the environment entries env,
+// without running them.
+func printScriptCommands(scriptPath, command string, args []string, env []string) (string, error) {
+	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("%s script not found", scriptPath)
+	}
+
+	services, err := readScriptServices(scriptPath)
+	if err != nil {
+		return "", err
+	}
+	if len(services) == 0 {
+		return "", fmt.Errorf("no compose commands found in %s", filepath.Base(scriptPath))
+	}
+
+	color.Yellow("Dry run: %s %s would run:", filepath.Base(scriptPath), command)
+	for _, c := range script.Commands(scriptCompose(scriptPath).Command, services, command, args) {
+		c.Env = env
+		if c.Shared {
+			fmt.Printf("%s  # project: %s, skipped when already running (shared service)\n", c, c.Project)
+		} else {
+			fmt.Printf("%s  # project: %s\n", c, c.Project)
+		}
+	}
+	if command == "down" {
+		for _, service := range services {
+			if service.Shared {
+				fmt.Printf("# %s is shared with other groups and is left running\n", service.ServiceName)
+			}
+		}
+	}
+	return scriptPath, nil
+}
@@ -0,0 +1,204 @@
+package dockermi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/engine"
+	"github.com/mkhuda/dockermi/internal/events"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/runner"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/internal/timing"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// defaultHealthTimeout bounds how long 'up --events' waits for a service to
+// become healthy.
+const defaultHealthTimeout = 2 * time.Minute
+
+// healthInterval is how often the engine is asked for the health of a service.
+const healthInterval = time.Second
+
+// runOptions holds the options of up and down runs reporting events.
+type runOptions struct {
+	// Events is the file JSON lines events are appended to.
+	Events string
+	// JUnit is the file a JUnit XML report is written to.
+	JUnit string
+	// HealthTimeout bounds the wait for each service to become healthy.
+	HealthTimeout time.Duration
+	// Profile prints the timing of the run, compared with the previous runs.
+	Profile bool
+
+	// report collects the JUnit report of every action of the command, see
+	// writeJUnit.
+	report *events.JUnit
+}
+
+// parseRunOptions reads the --events, --junit, --health-timeout and --profile
+// options and returns the remaining args.
+func parseRunOptions(args []string) (runOptions, []string, error) {
+	opts := runOptions{HealthTimeout: defaultHealthTimeout}
+	opts.Profile, args = popFlag(args, "profile")
+	var err error
+	if opts.Events, args, err = popFlagValue(args, "events"); err != nil {
+		return opts, nil, err
+	}
+	if opts.JUnit, args, err = popFlagValue(args, "junit"); err != nil {
+		return opts, nil, err
+	}
+	if opts.JUnit != "" {
+		opts.report = events.NewJUnit()
+	}
+
+	timeout, args, err := popFlagValue(args, "health-timeout")
+	if err != nil {
+		return opts, nil, err
+	}
+	if timeout != "" {
+		if opts.HealthTimeout, err = time.ParseDuration(timeout); err != nil {
+			return opts, nil, fmt.Errorf("invalid --health-timeout: %w", err)
+		}
+	}
+	return opts, args, nil
+}
+
+// enabled reports whether the run reports events or timings, which makes
+// dockermi run the compose commands itself instead of running the script.
+func (o runOptions) enabled() bool {
+	return o.Events != "" || o.JUnit != "" || o.Profile
+}
+
+// runServices performs command ("up" or "down") for the services of the script
+// at scriptPath with the runner, phase by phase, instead of running the script.
+// Progress is printed on the console, events are appended to the --events
+// file and collected for the --junit report. On up every service is waited for until it is healthy,
+// and the timing of the run is added to the history in .dockermi/runs.
+func runServices(projectDir, scriptPath, command string, args, env []string, target compose.Target, opts runOptions) error {
+	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+		return fmt.Errorf("%s script not found", scriptPath)
+	}
+
+	services, err := readScriptServices(scriptPath)
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("no services found in %s", scriptPath)
+	}
+	// The script does not record dockermi.order, so the phases come from the
+	// compose files; the services and their order stay those of the script.
+	root, discover := scriptServices(projectDir, scriptPath)
+	if discovered, derr := discover(); derr == nil {
+		orders := make(map[string]string, len(discovered))
+		for _, service := range discovered {
+			orders[plan.ID(service)] = service.Order
+		}
+		for i := range services {
+			services[i].Order = orders[plan.ID(services[i])]
+		}
+	}
+
+	observers := events.Multi{events.Console{}}
+	var sinkErrors []func() error
+	if opts.Events != "" {
+		file, err := os.OpenFile(opts.Events, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		jsonl := events.NewJSONLines(file)
+		observers = append(observers, jsonl)
+		sinkErrors = append(sinkErrors, func() error { return jsonl.Err })
+	}
+	if opts.report != nil {
+		observers = append(observers, opts.report)
+	}
+
+	var recorder *timing.Recorder
+	if command == "up" {
+		recorder = timing.NewRecorder(scriptLabel(scriptPath))
+		observers = append(observers, recorder)
+	}
+
+	r := runner.Runner{Command: scriptCompose(scriptPath).Command, Env: env, Args: args, Observer: observers}
+	if command == "up" {
+		r.Wait, r.Running = engineChecks(target, opts.HealthTimeout)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = r.Run(ctx, command, services)
+	for _, sinkErr := range sinkErrors {
+		if serr := sinkErr(); serr != nil && err == nil {
+			err = fmt.Errorf("cannot write events: %w", serr)
+		}
+	}
+	if recorder == nil {
+		return err
+	}
+	return saveTiming(root, recorder, opts.Profile, err)
+}
+
+// writeJUnit writes the JUnit report of the actions run with opts, e.g. both
+// the down and up runs of a restart, to the --junit file. err is the outcome
+// of the runs, returned unless writing the report fails.
+func writeJUnit(opts runOptions, err error) error {
+	if opts.report == nil {
+		return err
+	}
+	file, werr := os.Create(opts.JUnit)
+	if werr == nil {
+		werr = opts.report.Write(file)
+		if cerr := file.Close(); werr == nil {
+			werr = cerr
+		}
+	}
+	if werr != nil && err == nil {
+		err = fmt.Errorf("cannot write the JUnit report: %w", werr)
+	}
+	return err
+}
+
+// engineChecks returns the runner Wait and Running functions checking services
+// through the engine of target, or nil functions when the engine cannot be
+// reached.
+func engineChecks(target compose.Target, timeout time.Duration) (func(context.Context, DockermiTypes.ServiceScript) (string, error), func(context.Context, DockermiTypes.ServiceScript) (bool, error)) {
+	client, err := engine.Connect(target)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		err = client.Ping(ctx)
+		cancel()
+	}
+	if err != nil {
+		color.Yellow("Warning: services are not checked for health: %v", err)
+		return nil, nil
+	}
+
+	running := func(ctx context.Context, service DockermiTypes.ServiceScript) (bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+		defer cancel()
+		containers, err := client.Containers(ctx, engine.ComposeFilters(script.ProjectName(service.ComposeFile), service.ServiceName))
+		if err != nil {
+			return false, err
+		}
+		for _, c := range containers {
+			if c.State == "running" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	wait := func(ctx context.Context, service DockermiTypes.ServiceScript) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return client.WaitHealthy(ctx, script.ProjectName(service.ComposeFile), service.ServiceName, healthInterval)
+	}
+	return wait, running
+}
@@ -0,0 +1,50 @@
+package dockermi_test
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestartJUnit(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+	writeCompose(t, projectDir, "app", service("api", "2"))
+	if out, err := runDockermi(t, projectDir); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+	report := filepath.Join(projectDir, "report.xml")
+	restart := dockermiCommand(t, projectDir, "restart", "--junit", report)
+	restart.Env = append(restart.Env, "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if out, err := restart.CombinedOutput(); err != nil {
+		t.Fatalf("Error running restart: %v\n%s", err, out)
+	}
+
+	// The report has the suites of both the down and the up run
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("Error reading the JUnit report: %v", err)
+	}
+	var suites struct {
+		Tests  int `xml:"tests,attr"`
+		Suites []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("Invalid JUnit report: %v\n%s", err, data)
+	}
+	var names []string
+	for _, suite := range suites.Suites {
+		names = append(names, suite.Name)
+	}
+	if suites.Tests != 4 || len(names) != 4 || names[0] != "dockermi down: order 2" || names[3] != "dockermi up: order 2" {
+		t.Fatalf("Expected the down and up suites, got %d tests in %v\n%s", suites.Tests, names, data)
+	}
+}
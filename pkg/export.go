@@ -0,0 +1,142 @@
+package dockermi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/export"
+	"github.com/mkhuda/dockermi/internal/script"
+)
+
+// handleExportCommand handles 'dockermi export <target>', converting the
+// services found in projectDir into another format.
+func handleExportCommand(projectDir string, args []string, opts generateOptions) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("missing target for export command (supported: systemd, k8s)")
+	}
+
+	switch args[0] {
+	case "systemd":
+		return exportSystemd(projectDir, args[1:], opts)
+	case "k8s", "kubernetes":
+		return exportKubernetes(projectDir, args[1:], opts)
+	default:
+		return "", fmt.Errorf("unknown export target: %s (supported: systemd, k8s)", args[0])
+	}
+}
+
+// exportData finds the services in projectDir and builds the data model shared
+// by all export targets. It returns false when there is nothing to export.
+func exportData(projectDir string, opts generateOptions) (script.Data, bool, error) {
+	services, err := dockercompose.FindServices(projectDir, opts.Force)
+	if err != nil {
+		return script.Data{}, false, err
+	}
+	if len(services) == 0 {
+		color.Yellow("No docker-compose.yml found within this folder")
+		return script.Data{}, false, nil
+	}
+
+	header, err := script.NewHeader(GetVersion(), projectDir, "", opts.flags(), services)
+	if err != nil {
+		return script.Data{}, false, err
+	}
+	data := script.NewData(services, header)
+	data.UseCompose(opts.composeTool())
+	data.UseTarget(opts.composeTool(), opts.Target)
+	return data, true, nil
+}
+
+// exportSystemd writes systemd units for the services found in projectDir.
+func exportSystemd(projectDir string, args []string, opts generateOptions) (string, error) {
+	output, args, err := popFlagValue(args, "output")
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		output = filepath.Join(projectDir, "systemd")
+	}
+	perPhase, args := popFlag(args, "per-phase")
+	groupTargets, args := popFlag(args, "group-targets")
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for export systemd: %s", args[0])
+	}
+
+	data, ok, err := exportData(projectDir, opts)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	// Rootless Podman runs containers in the session of the user, so the units
+	// are user units
+	rootless := data.Podman && os.Geteuid() != 0
+	written, err := export.Systemd(data, export.SystemdOptions{
+		OutputDir:    output,
+		PerPhase:     perPhase,
+		GroupTargets: groupTargets,
+		Rootless:     rootless,
+	})
+	for _, path := range written {
+		color.Cyan("Wrote %s", path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Println()
+	color.Green("Generated %d systemd unit(s) in %s", len(written), output)
+	if rootless {
+		color.Blue("Copy them to ~/.config/systemd/user, then run [systemctl --user daemon-reload] and [systemctl --user enable --now <unit>]")
+	} else {
+		color.Blue("Copy them to /etc/systemd/system, then run [systemctl daemon-reload] and [systemctl enable --now <unit>]")
+	}
+	return output, nil
+}
+
+// exportKubernetes writes Kubernetes manifests for the services found in projectDir
+// to the --output file, or to stdout when no file is given.
+func exportKubernetes(projectDir string, args []string, opts generateOptions) (string, error) {
+	output, args, err := popFlagValue(args, "output")
+	if err != nil {
+		return "", err
+	}
+	waitImage, args, err := popFlagValue(args, "wait-image")
+	if err != nil {
+		return "", err
+	}
+	namespaceByKey, args := popFlag(args, "namespace-by-key")
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for export k8s: %s", args[0])
+	}
+
+	if output == "" {
+		// Keep stdout clean for the manifests, e.g. when piped into kubectl apply -f -
+		color.Output = os.Stderr
+	}
+
+	data, ok, err := exportData(projectDir, opts)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	k8sOpts := export.KubernetesOptions{NamespaceByKey: namespaceByKey, WaitImage: waitImage}
+	if output == "" {
+		return "", export.Kubernetes(os.Stdout, data, k8sOpts)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Kubernetes(&buf, data, k8sOpts); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	color.Green("Generated Kubernetes manifests: %s", output)
+	color.Blue("Apply them with [kubectl apply -f %s]", output)
+	return output, nil
+}
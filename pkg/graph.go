@@ -0,0 +1,76 @@
+package dockermi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/graph"
+)
+
+// handleGraphCommand handles 'dockermi graph', rendering the start plan of
+// projectDir as a dot, mermaid or json graph to the --output file or stdout.
+// format is the value of the global --format flag, if any.
+func handleGraphCommand(projectDir string, args []string, force bool, format string) (string, error) {
+	forced, args := popFlag(args, "force")
+	value, args, err := popFlagValue(args, "format")
+	if err != nil {
+		return "", err
+	}
+	if value != "" {
+		format = value
+	}
+	if format == "" {
+		format = "dot"
+	}
+	cluster, args, err := popFlagValue(args, "cluster")
+	if err != nil {
+		return "", err
+	}
+	if cluster == "" {
+		cluster = graph.ClusterFile
+	}
+	output, args, err := popFlagValue(args, "output")
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for graph: %s", args[0])
+	}
+
+	if output == "" {
+		// Keep stdout clean for the graph, e.g. when piped into dot -Tsvg
+		color.Output = os.Stderr
+	}
+
+	services, err := dockercompose.FindAllServices(projectDir, force || forced)
+	if err != nil {
+		return "", err
+	}
+	if len(services) == 0 {
+		color.Yellow("No docker-compose.yml found within this folder")
+		return "", nil
+	}
+
+	g, err := graph.New(projectDir, services, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	if output == "" {
+		return "", graph.Write(os.Stdout, g, format)
+	}
+
+	var buf bytes.Buffer
+	if err := graph.Write(&buf, g, format); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	color.Green("Generated %s graph: %s", format, output)
+	return output, nil
+}
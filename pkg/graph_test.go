@@ -0,0 +1,19 @@
+package dockermi_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphUnknownArgument(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+
+	out, err := runDockermi(t, projectDir, "graph", "--format", "json", "--ouput", "plan.json")
+	if err == nil || !strings.Contains(out, "unknown argument for graph: --ouput") {
+		t.Fatalf("Expected the misspelled option to be rejected, got: %v\n%s", err, out)
+	}
+	if out, err := runDockermi(t, projectDir, "graph", "--format", "json"); err != nil || !strings.Contains(out, `"db"`) {
+		t.Fatalf("Expected the graph to be printed, got: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,215 @@
+package dockermi
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/groupkey"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/registry"
+	"github.com/mkhuda/dockermi/internal/script"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// handleGroupsCommand handles the 'groups' command and its list/show/context/rm
+// subcommands. target holds the --context or --host option of 'groups context'.
+func handleGroupsCommand(args []string, target compose.Target) (string, error) {
+	if len(args) == 0 {
+		return listGroups()
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return listGroups()
+	case "show":
+		if len(args) < 2 {
+			return "", fmt.Errorf("missing key for groups show command")
+		}
+		return showGroup(args[1])
+	case "context":
+		if len(args) < 2 {
+			return "", fmt.Errorf("missing key for groups context command")
+		}
+		return setGroupContext(args[1], args[2:], target)
+	case "rm", "remove":
+		if len(args) < 2 {
+			return "", fmt.Errorf("missing key for groups rm command")
+		}
+		return removeGroups(args[1:])
+	default:
+		return "", fmt.Errorf("unknown groups command: %s", args[0])
+	}
+}
+
+// listGroups prints every group script recorded in the registry.
+func listGroups() (string, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+
+	entries := reg.List()
+	if len(entries) == 0 {
+		color.Yellow("No group scripts found. Use [dockermi create <key>] to create one.")
+		return "", nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSERVICES\tCREATED\tROOT")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", e.Key, e.ServiceCount, e.CreatedAt.Format(time.RFC3339), e.Root)
+	}
+	return "", w.Flush()
+}
+
+// showGroup prints the registry details of a single group script.
+func showGroup(key string) (string, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+
+	e, ok := reg.Get(key)
+	if !ok {
+		return "", fmt.Errorf("group not found: %s", key)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Key:\t%s\n", e.Key)
+	fmt.Fprintf(w, "Script:\t%s\n", e.ScriptPath)
+	fmt.Fprintf(w, "Source root:\t%s\n", e.Root)
+	fmt.Fprintf(w, "Created:\t%s\n", e.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Services:\t%d\n", e.ServiceCount)
+	fmt.Fprintf(w, "Generator:\t%s\n", e.Version)
+	if target := entryTarget(e); !target.Empty() {
+		fmt.Fprintf(w, "Engine:\t%s\n", target)
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(e.ScriptPath); os.IsNotExist(err) {
+		color.Yellow("Script %s is missing. Run [dockermi create %s] from %s to regenerate it.", e.ScriptPath, e.Key, e.Root)
+	}
+	return e.ScriptPath, nil
+}
+
+// setGroupContext handles 'dockermi groups context <key> [<context> | --host <host> | --clear]',
+// printing or changing the default target of a group. The group script keeps
+// the target it was generated with; the default applies to 'up --group' and
+// 'status --group' and to the next 'dockermi create <key>'.
+func setGroupContext(key string, args []string, target compose.Target) (string, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+	e, ok := reg.Get(key)
+	if !ok {
+		return "", fmt.Errorf("group not found: %s", key)
+	}
+
+	unset, args := popFlag(args, "clear")
+	if len(args) > 1 || (len(args) == 1 && !target.Empty()) {
+		return "", fmt.Errorf("usage: dockermi groups context <key> [<context> | --host <host> | --clear]")
+	}
+	if len(args) == 1 {
+		target.Context = args[0]
+	}
+
+	if unset {
+		target = compose.Target{}
+	} else if target.Empty() {
+		fmt.Printf("%s: %s\n", key, entryTarget(e))
+		return e.ScriptPath, nil
+	}
+
+	e.Context, e.Host = target.Context, target.Host
+	reg.Put(e)
+	if err := reg.Save(); err != nil {
+		return "", err
+	}
+	if unset {
+		color.Green("Group %s uses the default engine", key)
+	} else {
+		color.Green("Group %s uses %s", key, target)
+	}
+	return e.ScriptPath, nil
+}
+
+// removeGroups deletes the given group scripts and their registry entries.
+func removeGroups(keys []string) (string, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range keys {
+		if err := reg.Remove(key); err != nil {
+			return "", err
+		}
+		color.Green("Removed group: %s", key)
+	}
+	return "", reg.Save()
+}
+
+// groupScriptPath resolves the script registered for key so it can be run from any directory.
+func groupScriptPath(key string) (string, error) {
+	e, err := groupEntry(key)
+	if err != nil {
+		return "", err
+	}
+	return e.ScriptPath, nil
+}
+
+// groupEntry returns the registry entry of the group key.
+func groupEntry(key string) (registry.Entry, error) {
+	if err := groupkey.Validate(key); err != nil {
+		return registry.Entry{}, err
+	}
+	reg, err := registry.Load()
+	if err != nil {
+		return registry.Entry{}, err
+	}
+
+	e, ok := reg.Get(key)
+	if !ok {
+		return registry.Entry{}, fmt.Errorf("group not found: %s (run [dockermi create %s] first)", key, key)
+	}
+	return e, nil
+}
+
+// readScriptServices returns the services the script at scriptPath runs, in
+// start order. The services of a group script are marked Shared as recorded in
+// the registry when the script was generated.
+func readScriptServices(scriptPath string) ([]DockermiTypes.ServiceScript, error) {
+	services, err := script.ReadScriptServices(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	header, found, err := script.ReadHeader(scriptPath)
+	if err != nil || !found || header.Key == "" {
+		return services, nil
+	}
+	reg, err := registry.Load()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := reg.Get(header.Key)
+	if !ok {
+		return services, nil
+	}
+
+	shared := make(map[string]bool, len(e.Shared))
+	for _, id := range e.Shared {
+		shared[id] = true
+	}
+	for i := range services {
+		services[i].Shared = shared[plan.ID(services[i])]
+	}
+	return services, nil
+}
@@ -0,0 +1,183 @@
+package dockermi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/audit"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/script"
+)
+
+// defaultHistoryRecords is the number of records 'dockermi history' prints by default.
+const defaultHistoryRecords = 20
+
+// auditRun runs command ("up" or "down") of the script at scriptPath with run
+// and appends a record of it to the audit log of the project. A record that
+// cannot be written only prints a warning.
+func auditRun(projectDir, scriptPath, group, command string, args []string, run func() error) error {
+	root, discover := scriptServices(projectDir, scriptPath)
+	record := audit.NewRecord(command)
+	record.Cwd = projectDir
+	record.Args = args
+	record.Group = group
+	record.Script = scriptPath
+	record.Version = GetVersion()
+	if header, found, err := script.ReadHeader(scriptPath); err == nil && found {
+		record.ScriptVersion = header.Version
+	}
+	if services, err := discover(); err == nil {
+		plan.Sort(services)
+		for _, service := range services {
+			record.Services = append(record.Services, service.ServiceName)
+		}
+	}
+
+	err := run()
+	record.Finish(err)
+	if werr := audit.Open(root).Append(record); werr != nil {
+		color.Yellow("Warning: cannot write the audit log: %v", werr)
+	}
+	return err
+}
+
+// handleHistoryCommand handles 'dockermi history', printing the last --last up
+// and down runs recorded in the audit log of the project, filtered by --group,
+// --service, --user, --since and --until. With --json the records are printed
+// as JSON lines.
+func handleHistoryCommand(projectDir string, args []string) (string, error) {
+	var filter audit.Filter
+	var since, until, lastValue string
+	var err error
+	if filter.Group, args, err = popFlagValue(args, "group"); err != nil {
+		return "", err
+	}
+	if filter.Service, args, err = popFlagValue(args, "service"); err != nil {
+		return "", err
+	}
+	if filter.User, args, err = popFlagValue(args, "user"); err != nil {
+		return "", err
+	}
+	if since, args, err = popFlagValue(args, "since"); err != nil {
+		return "", err
+	}
+	if until, args, err = popFlagValue(args, "until"); err != nil {
+		return "", err
+	}
+	if lastValue, args, err = popFlagValue(args, "last"); err != nil {
+		return "", err
+	}
+	asJSON, args := popFlag(args, "json")
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for history: %s", args[0])
+	}
+
+	now := time.Now()
+	if since != "" {
+		if filter.Since, err = parseHistoryTime(since, now, false); err != nil {
+			return "", fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = parseHistoryTime(until, now, true); err != nil {
+			return "", fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	last := defaultHistoryRecords
+	if lastValue != "" {
+		if last, err = strconv.Atoi(lastValue); err != nil || last < 0 {
+			return "", fmt.Errorf("invalid --last: %s", lastValue)
+		}
+	}
+
+	scriptPath := projectScriptPath(projectDir)
+	if filter.Group != "" {
+		if scriptPath, err = groupScriptPath(filter.Group); err != nil {
+			return "", err
+		}
+	}
+	root, _ := scriptServices(projectDir, scriptPath)
+	records, err := audit.Open(root).Read(filter)
+	if err != nil {
+		return "", err
+	}
+	if last > 0 && len(records) > last {
+		records = records[len(records)-last:]
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return "", err
+			}
+		}
+		return scriptPath, nil
+	}
+	if len(records) == 0 {
+		color.Yellow("No runs recorded in %s", audit.Open(root).Path)
+		return scriptPath, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tHOST\tCOMMAND\tSERVICES\tOUTCOME\tDURATION\tVERSION")
+	for _, record := range records {
+		command := strings.TrimSpace(strings.Join(append([]string{record.Command}, record.Args...), " "))
+		if record.Group != "" {
+			command += " --group " + record.Group
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Time.Local().Format("2006-01-02 15:04:05"), record.User, record.Host, command,
+			summarizeServices(record.Services), record.Outcome,
+			formatDuration(time.Duration(record.Duration*float64(time.Second))), record.Version)
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	if latest := records[len(records)-1]; latest.Error != "" {
+		fmt.Println()
+		color.Red("The last run failed: %s", latest.Error)
+	}
+	return scriptPath, nil
+}
+
+// parseHistoryTime parses a --since or --until value: a date (2006-01-02), a
+// date and time (RFC 3339 or 2006-01-02 15:04) in local time, or a duration
+// before now such as 36h. A date given for --until (end) includes that day.
+func parseHistoryTime(value string, now time.Time, end bool) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02), a time (2006-01-02 15:04) or a duration (24h)", value)
+}
+
+// summarizeServices returns the first services of a record, and how many
+// others there are.
+func summarizeServices(services []string) string {
+	const shown = 3
+	if len(services) == 0 {
+		return "-"
+	}
+	if len(services) <= shown {
+		return strings.Join(services, ",")
+	}
+	return fmt.Sprintf("%s +%d", strings.Join(services[:shown], ","), len(services)-shown)
+}
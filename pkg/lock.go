@@ -0,0 +1,91 @@
+package dockermi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/audit"
+	"github.com/mkhuda/dockermi/internal/lock"
+)
+
+// defaultLockTimeout bounds how long --wait-lock waits for the lock.
+const defaultLockTimeout = 10 * time.Minute
+
+// lockInterval is how often a held lock is tried again with --wait-lock.
+const lockInterval = time.Second
+
+// lockOptions holds the options of up and down runs about the run lock.
+type lockOptions struct {
+	// Wait waits for a held lock instead of failing.
+	Wait bool
+	// Timeout bounds the wait.
+	Timeout time.Duration
+}
+
+// parseLockOptions reads the --wait-lock and --lock-timeout options and
+// returns the remaining args.
+func parseLockOptions(args []string) (lockOptions, []string, error) {
+	opts := lockOptions{Timeout: defaultLockTimeout}
+	opts.Wait, args = popFlag(args, "wait-lock")
+
+	timeout, args, err := popFlagValue(args, "lock-timeout")
+	if err != nil {
+		return opts, nil, err
+	}
+	if timeout != "" {
+		if opts.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return opts, nil, fmt.Errorf("invalid --lock-timeout: %w", err)
+		}
+	}
+	return opts, args, nil
+}
+
+// lockPath returns the lock file of the script at scriptPath: the one of its
+// project root, which group scripts share with the project.
+func lockPath(projectDir, scriptPath string) string {
+	root, _ := scriptServices(projectDir, scriptPath)
+	return lock.Path(root)
+}
+
+// lockRun runs run while holding the lock of the script at scriptPath, so
+// that two runs of the same services, or of groups sharing services, do not
+// overlap. When another run holds the lock, lockRun fails, or with opts.Wait
+// waits for it up to opts.Timeout. The lock of a run that was killed is
+// released by the system.
+func lockRun(projectDir, scriptPath, group, command string, opts lockOptions, run func() error) error {
+	path := lockPath(projectDir, scriptPath)
+	if group != "" {
+		command += " --group " + group
+	}
+	info := lock.NewInfo(audit.CurrentUser(), command)
+
+	var l *lock.Lock
+	var err error
+	if opts.Wait {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+		l, err = lock.Wait(ctx, path, info, lockInterval, func(holder lock.Info) {
+			color.Yellow("Waiting for %s, locked by %s...", filepath.Base(scriptPath), holder)
+		})
+	} else {
+		l, err = lock.Acquire(path, info)
+	}
+
+	var held *lock.HeldError
+	if errors.As(err, &held) && !opts.Wait {
+		return fmt.Errorf("%s is %w; use --wait-lock to wait for it", filepath.Base(scriptPath), held)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot lock %s: %w", filepath.Base(scriptPath), err)
+	}
+
+	err = run()
+	if rerr := l.Release(); rerr != nil {
+		color.Yellow("Warning: cannot remove the lock %s: %v", l.Path(), rerr)
+	}
+	return err
+}
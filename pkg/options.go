@@ -0,0 +1,220 @@
+package dockermi
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/script"
+)
+
+// generateOptions holds the flags that control script generation. They are
+// recorded in the script header so that a script can later be regenerated
+// with the same settings.
+type generateOptions struct {
+	Force    bool
+	Template string
+	Format   string
+	// DryRun prints what would be done instead of writing scripts or running
+	// compose commands. It is not recorded in the script header.
+	DryRun bool
+	// Overwrite allows replacing scripts that have no dockermi header. Like
+	// DryRun it only applies to the current run.
+	Overwrite bool
+	// Compose names the compose tool the scripts invoke (see compose.Tools),
+	// or is autoCompose to detect the installed tool. When empty the tool
+	// named by DOCKERMI_COMPOSE, or else compose.Default, is used.
+	Compose string
+	// Target is the docker context or engine host the scripts use unless
+	// their environment selects one.
+	Target compose.Target
+}
+
+// parseGenerateOptions reads generation flags given after a subcommand
+// (e.g. 'dockermi create key --template file') on top of the global ones.
+func parseGenerateOptions(args []string, opts generateOptions) (generateOptions, []string, error) {
+	force, args := popFlag(args, "force")
+	opts.Force = opts.Force || force
+
+	dryRun, args := popFlag(args, "dry-run")
+	opts.DryRun = opts.DryRun || dryRun
+
+	overwrite, args := popFlag(args, "overwrite")
+	opts.Overwrite = opts.Overwrite || overwrite
+
+	template, args, err := popFlagValue(args, "template")
+	if err != nil {
+		return opts, nil, err
+	}
+	if template != "" {
+		opts.Template = template
+	}
+
+	format, args, err := popFlagValue(args, "format")
+	if err != nil {
+		return opts, nil, err
+	}
+	if format != "" {
+		opts.Format = format
+	}
+	if _, err := script.LookupFormat(opts.Format); err != nil {
+		return opts, nil, err
+	}
+
+	composeName, args, err := popFlagValue(args, "compose")
+	if err != nil {
+		return opts, nil, err
+	}
+	if composeName != "" {
+		opts.Compose = composeName
+	}
+	if opts.Compose != "" && opts.Compose != autoCompose {
+		if _, err := compose.Lookup(opts.Compose); err != nil {
+			return opts, nil, err
+		}
+	}
+
+	if opts.Target, args, err = parseTarget(args, opts.Target); err != nil {
+		return opts, nil, err
+	}
+
+	if opts.Template != "" {
+		if opts.Template, err = filepath.Abs(opts.Template); err != nil {
+			return opts, nil, err
+		}
+	}
+	return opts, args, nil
+}
+
+// optionsFromHeader restores the generation options recorded in a script header.
+// A header without --compose was generated for the default compose tool.
+func optionsFromHeader(header script.Header) generateOptions {
+	opts := generateOptions{
+		Force:    header.HasFlag("--force"),
+		Template: header.FlagValue("--template"),
+		Format:   header.FlagValue("--format"),
+		Compose:  header.FlagValue("--compose"),
+		Target: compose.Target{
+			Context: header.FlagValue("--context"),
+			Host:    header.FlagValue("--host"),
+		},
+	}
+	if opts.Compose == "" {
+		opts.Compose = compose.Default
+	}
+	return opts
+}
+
+// parseTarget reads the --context and --host options on top of target and
+// returns the remaining args. A context given after a subcommand replaces a
+// global host and the other way round.
+func parseTarget(args []string, target compose.Target) (compose.Target, []string, error) {
+	contextName, args, err := popFlagValue(args, "context")
+	if err != nil {
+		return target, nil, err
+	}
+	host, args, err := popFlagValue(args, "host")
+	if err != nil {
+		return target, nil, err
+	}
+
+	if contextName != "" || host != "" {
+		target = compose.Target{Context: contextName, Host: host}
+	}
+	return target, args, target.Validate()
+}
+
+// scriptCompose returns the compose tool the script at scriptPath invokes,
+// according to its header.
+func scriptCompose(scriptPath string) compose.Tool {
+	return headerOptions(scriptPath).composeTool()
+}
+
+// headerOptions returns the options recorded in the header of the script at
+// scriptPath, or the defaults when it has none.
+func headerOptions(scriptPath string) generateOptions {
+	header, found, err := script.ReadHeader(scriptPath)
+	if err != nil || !found {
+		header = script.Header{}
+	}
+	return optionsFromHeader(header)
+}
+
+// flags returns the options as they are recorded in the script header.
+func (o generateOptions) flags() []string {
+	var flags []string
+	if o.Force {
+		flags = append(flags, "--force")
+	}
+	if o.Template != "" {
+		flags = append(flags, "--template="+o.Template)
+	}
+	if o.Format != "" && o.Format != script.DefaultFormat {
+		flags = append(flags, "--format="+o.Format)
+	}
+	if tool := o.composeTool(); tool.Name != compose.Default {
+		flags = append(flags, "--compose="+tool.Name)
+	}
+	if o.Target.Context != "" {
+		flags = append(flags, "--context="+o.Target.Context)
+	}
+	if o.Target.Host != "" {
+		flags = append(flags, "--host="+o.Target.Host)
+	}
+	return flags
+}
+
+// scriptOptions converts the options for the script package. A progress bar
+// is only drawn when stdout is a terminal.
+func (o generateOptions) scriptOptions() script.Options {
+	opts := script.Options{Template: o.Template, Format: o.Format, Overwrite: o.Overwrite, Compose: o.composeTool().Name, Target: o.Target}
+	if isTerminal(os.Stdout) {
+		opts.Observer = script.NewProgressBar(os.Stdout)
+	}
+	return opts
+}
+
+// extension returns the file extension of the scripts generated with these options.
+func (o generateOptions) extension() string {
+	format, err := script.LookupFormat(o.Format)
+	if err != nil {
+		return ".sh"
+	}
+	return format.Extension
+}
+
+// autoCompose is the --compose value asking for the installed tool to be detected.
+const autoCompose = "auto"
+
+// composeTool returns the compose tool named by the options or DOCKERMI_COMPOSE,
+// or else compose.Default. The installed tool is only detected for
+// --compose auto, so that the generated scripts do not depend on what happens
+// to be on PATH; the default tool is used when nothing is installed.
+func (o generateOptions) composeTool() compose.Tool {
+	name := o.Compose
+	if name == "" {
+		name = os.Getenv("DOCKERMI_COMPOSE")
+	}
+	if name != autoCompose {
+		if tool, err := compose.Lookup(name); err == nil {
+			return tool
+		}
+		tool, _ := compose.Lookup(compose.Default)
+		return tool
+	}
+
+	detectOnce.Do(func() {
+		var err error
+		if detectedTool, err = compose.Detect(); err != nil {
+			detectedTool, _ = compose.Lookup(compose.Default)
+		}
+	})
+	return detectedTool
+}
+
+// detectedTool caches the result of compose.Detect for the current run.
+var (
+	detectOnce   sync.Once
+	detectedTool compose.Tool
+)
@@ -0,0 +1,57 @@
+package dockermi
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// projectScriptPath returns the dockermi script to run in projectDir. On Windows
+// dockermi.ps1 is preferred, on other platforms dockermi.sh; the other one is
+// used when it is the only script present.
+func projectScriptPath(projectDir string) string {
+	preferred, fallback := "dockermi.sh", "dockermi.ps1"
+	if runtime.GOOS == "windows" {
+		preferred, fallback = fallback, preferred
+	}
+
+	if _, err := os.Stat(filepath.Join(projectDir, preferred)); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(projectDir, fallback)); err == nil {
+			return filepath.Join(projectDir, fallback)
+		}
+	}
+	return filepath.Join(projectDir, preferred)
+}
+
+// scriptCommand prepares the command running scriptPath with args using the
+// interpreter matching the script extension.
+func scriptCommand(scriptPath string, args []string) *exec.Cmd {
+	if strings.EqualFold(filepath.Ext(scriptPath), ".ps1") {
+		// Windows PowerShell ships with Windows, PowerShell Core (pwsh) elsewhere
+		shell := "pwsh"
+		if runtime.GOOS == "windows" {
+			shell = "powershell"
+		}
+		return exec.Command(shell, append([]string{"-NoProfile", "-ExecutionPolicy", "Bypass", "-File", scriptPath}, args...)...)
+	}
+	return exec.Command(interpreter(scriptPath), append([]string{scriptPath}, args...)...)
+}
+
+// interpreter returns the shell named in the shebang of a shell script,
+// falling back to bash for scripts generated before --format sh existed.
+func interpreter(scriptPath string) string {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return "bash"
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	if strings.TrimSpace(line) == "#!/bin/sh" {
+		return "sh"
+	}
+	return "bash"
+}
@@ -0,0 +1,151 @@
+package dockermi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/events"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/internal/timing"
+)
+
+// defaultStatsRuns is the number of runs 'dockermi stats' compares by default.
+const defaultStatsRuns = 5
+
+// defaultRegressionThreshold is how much slower than its average a service
+// must be to be flagged, as a fraction.
+const defaultRegressionThreshold = 0.2
+
+// handleStatsCommand handles 'dockermi stats', comparing the timing of the last
+// up run of the dockermi script, or of a group script with --group, with the
+// runs before it.
+func handleStatsCommand(projectDir string, args []string) (string, error) {
+	group, args, err := popFlagValue(args, "group")
+	if err != nil {
+		return "", err
+	}
+	last, args, err := popFlagValue(args, "last")
+	if err != nil {
+		return "", err
+	}
+	threshold, args, err := popFlagValue(args, "threshold")
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for stats: %s", args[0])
+	}
+
+	n := defaultStatsRuns
+	if last != "" {
+		if n, err = strconv.Atoi(last); err != nil || n < 1 {
+			return "", fmt.Errorf("invalid --last: %s", last)
+		}
+	}
+	limit := defaultRegressionThreshold
+	if threshold != "" {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil || percent < 0 {
+			return "", fmt.Errorf("invalid --threshold: %s", threshold)
+		}
+		limit = percent / 100
+	}
+
+	scriptPath := projectScriptPath(projectDir)
+	if group != "" {
+		if scriptPath, err = groupScriptPath(group); err != nil {
+			return "", err
+		}
+	}
+
+	root, _ := scriptServices(projectDir, scriptPath)
+	runs, err := timing.Load(timing.Dir(root), scriptLabel(scriptPath), n)
+	if err != nil {
+		return "", err
+	}
+	if len(runs) == 0 {
+		color.Yellow("No runs recorded for %s. Run [dockermi up] to record one.", filepath.Base(scriptPath))
+		return "", nil
+	}
+	return scriptPath, printTimingReport(runs, limit)
+}
+
+// scriptLabel identifies the script at scriptPath in the run history: its
+// group key, or its file name.
+func scriptLabel(scriptPath string) string {
+	if header, found, err := script.ReadHeader(scriptPath); err == nil && found && header.Key != "" {
+		return header.Key
+	}
+	return filepath.Base(scriptPath)
+}
+
+// printTimingReport prints the timing of the last successful run of runs,
+// slowest services first, compared with the average of the successful runs
+// before it. Rows slower than their average by more than threshold are flagged.
+func printTimingReport(runs []timing.Run, threshold float64) error {
+	successful := timing.Successful(runs)
+	if failed := len(runs) - len(successful); failed > 0 {
+		color.Yellow("Skipping %d failed run(s)", failed)
+	}
+	if len(successful) == 0 {
+		return nil
+	}
+
+	latest := successful[len(successful)-1]
+	if len(successful) > 1 {
+		color.Cyan("Run of %s compared with the average of the %d run(s) before it:", latest.Started.Local().Format(time.RFC1123), len(successful)-1)
+	} else {
+		color.Cyan("Run of %s:", latest.Started.Local().Format(time.RFC1123))
+	}
+
+	rows := timing.Compare(successful, threshold)
+	var regressions []string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tPHASE\tTOTAL\tCOMMAND\tHEALTHY\tAVERAGE\tCHANGE\t")
+	for _, row := range rows {
+		name, command, healthy := row.Name, "", ""
+		switch row.Kind {
+		case timing.RowService:
+			command, healthy = formatDuration(row.Command), formatDuration(row.Healthy)
+		case timing.RowPhase:
+			name = "(phase " + events.PhaseName(row.Name) + ")"
+		case timing.RowTotal:
+			name = "(total)"
+		}
+
+		average, change, flag := "-", "-", ""
+		if row.Samples > 0 {
+			average = formatDuration(row.Baseline)
+			change = fmt.Sprintf("%+.0f%%", row.Change()*100)
+		}
+		if row.Regression {
+			flag = "regression"
+			regressions = append(regressions, name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, events.PhaseName(row.Phase), formatDuration(row.Latest), command, healthy, average, change, flag)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(regressions) > 0 {
+		fmt.Println()
+		color.Red("%d regression(s) slower than the average by more than %.0f%%: %s", len(regressions), threshold*100, strings.Join(regressions, ", "))
+	}
+	return nil
+}
+
+// formatDuration shortens d for the timing report: to the millisecond below
+// a second, to 10ms above.
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
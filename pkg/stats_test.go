@@ -0,0 +1,52 @@
+package dockermi_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatsUnknownArgument(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+	if out, err := runDockermi(t, projectDir); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+
+	out, err := runDockermi(t, projectDir, "stats", "--treshold", "10%")
+	if err == nil || !strings.Contains(out, "unknown argument for stats: --treshold") {
+		t.Fatalf("Expected the misspelled option to be rejected, got: %v\n%s", err, out)
+	}
+}
+
+func TestStatsPlainUp(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+	writeCompose(t, projectDir, "app", service("api", "2"))
+	if out, err := runDockermi(t, projectDir); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+
+	// A fake docker-compose takes a while instead of talking to Docker
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "docker-compose"), []byte("#!/bin/sh\nsleep 0.1\n"), 0755); err != nil {
+		t.Fatalf("Error writing fake docker-compose: %v", err)
+	}
+	up := dockermiCommand(t, projectDir, "up")
+	up.Env = append(up.Env, "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if out, err := up.CombinedOutput(); err != nil {
+		t.Fatalf("Error running up: %v\n%s", err, out)
+	}
+
+	// The run of the script is timed service by service
+	out, err := runDockermi(t, projectDir, "stats")
+	if err != nil {
+		t.Fatalf("Error running stats: %v\n%s", err, out)
+	}
+	for _, name := range []string{"db", "api", "(phase 1)", "(phase 2)"} {
+		if !strings.Contains(out, name) {
+			t.Fatalf("Expected the timing of %s, got:\n%s", name, out)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package dockermi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/engine"
+	"github.com/mkhuda/dockermi/internal/lock"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/script"
+)
+
+// statusTimeout bounds the engine requests made by 'dockermi status'.
+const statusTimeout = 10 * time.Second
+
+// handleStatusCommand handles 'dockermi status', showing the container state and
+// health of every service of the dockermi script, or of a group script with --group.
+// target selects the engine (see engineTarget). The holder of the run lock, if
+// any, is shown last.
+func handleStatusCommand(projectDir string, args []string, target compose.Target) (string, error) {
+	group, _, err := popFlagValue(args, "group")
+	if err != nil {
+		return "", err
+	}
+
+	scriptPath := projectScriptPath(projectDir)
+	if group != "" {
+		if scriptPath, err = groupScriptPath(group); err != nil {
+			return "", err
+		}
+	}
+
+	_, discover := scriptServices(projectDir, scriptPath)
+	services, err := discover()
+	if err != nil {
+		return "", err
+	}
+	if len(services) == 0 {
+		color.Yellow("No services found for %s", scriptPath)
+		return "", nil
+	}
+	plan.Sort(services)
+
+	if target, err = engineTarget(scriptPath, group, target); err != nil {
+		return "", err
+	}
+	client, err := engine.Connect(target)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+
+	// List the containers of each compose project once
+	containers := make(map[string]map[string][]engine.Container) // project -> service -> containers
+	running := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORDER\tSERVICE\tPROJECT\tSTATE\tHEALTH")
+	for _, service := range services {
+		project := script.ProjectName(service.ComposeFile)
+		if _, ok := containers[project]; !ok {
+			list, err := client.Containers(ctx, engine.ComposeFilters(project, ""))
+			if err != nil {
+				return "", err
+			}
+			containers[project] = make(map[string][]engine.Container)
+			for _, c := range list {
+				containers[project][c.Service()] = append(containers[project][c.Service()], c)
+			}
+		}
+
+		list := containers[project][service.ServiceName]
+		if len(list) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", service.Order, service.ServiceName, project, "not created", "-")
+			continue
+		}
+		serviceRunning := false
+		for _, c := range list {
+			health := "-"
+			if state, err := client.Inspect(ctx, c.ID); err == nil && state.HealthStatus() != "" {
+				health = state.HealthStatus()
+			}
+			serviceRunning = serviceRunning || c.State == "running"
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", service.Order, service.ServiceName, project, c.State, health)
+		}
+		if serviceRunning {
+			running++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	fmt.Println()
+	if running < len(services) {
+		color.Yellow("%d of %d service(s) running", running, len(services))
+	} else {
+		color.Green("All %d service(s) running", len(services))
+	}
+	if holder, err := lock.Read(lockPath(projectDir, scriptPath)); err == nil {
+		if holder.Stale() {
+			color.Yellow("Stale lock left by %s", holder)
+		} else {
+			color.Yellow("Locked by %s", holder)
+		}
+	}
+	return scriptPath, nil
+}
@@ -0,0 +1,39 @@
+package dockermi
+
+import (
+	"os"
+
+	"github.com/mkhuda/dockermi/internal/compose"
+	"github.com/mkhuda/dockermi/internal/registry"
+)
+
+// engineTarget resolves the engine used with the script at scriptPath. In order
+// of precedence: the --context or --host option, the default of the group in
+// the registry, the environment and the target recorded in the script header.
+// The zero Target leaves the choice to the environment.
+func engineTarget(scriptPath, group string, target compose.Target) (compose.Target, error) {
+	if !target.Empty() {
+		return target, nil
+	}
+	if group != "" {
+		e, err := groupEntry(group)
+		if err != nil {
+			return target, err
+		}
+		if target = entryTarget(e); !target.Empty() {
+			return target, nil
+		}
+	}
+
+	tool := scriptCompose(scriptPath)
+	contextVariable, hostVariable := tool.Variables()
+	if os.Getenv(contextVariable) != "" || os.Getenv(hostVariable) != "" {
+		return compose.Target{}, nil
+	}
+	return headerOptions(scriptPath).Target, nil
+}
+
+// entryTarget returns the default target of a group.
+func entryTarget(e registry.Entry) compose.Target {
+	return compose.Target{Context: e.Context, Host: e.Host}
+}
@@ -0,0 +1,189 @@
+package dockermi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/events"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/timing"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// startingLine and skippingLine match the lines generated scripts print before
+// starting a service, and when they skip a shared service already running.
+var (
+	startingLine = regexp.MustCompile(`^Starting (.+)\.\.\.$`)
+	skippingLine = regexp.MustCompile(`^(.+) is already running \(shared service\)\. Skipping\.\.\.$`)
+)
+
+// scriptTimer passes the output of a generated script through to out and
+// times the services the script starts from the line it prints before each of
+// them, sending the events a runner.Runner would send to observer. Scripts
+// do not wait for services to become healthy, so a service takes from its
+// line to the next one, all of it counted as its compose command.
+type scriptTimer struct {
+	out      io.Writer
+	observer events.Observer
+	// services are the services of the script in start order; next is the
+	// index of the first one not seen yet.
+	services []DockermiTypes.ServiceScript
+	next     int
+	partial  []byte
+
+	start      time.Time
+	current    *events.Event
+	since      time.Time
+	phase      *events.Event
+	phaseSince time.Time
+}
+
+// newScriptTimer returns a scriptTimer for an up run of services.
+func newScriptTimer(out io.Writer, observer events.Observer, services []DockermiTypes.ServiceScript) *scriptTimer {
+	t := &scriptTimer{out: out, observer: observer, services: services, start: time.Now()}
+	resolved := events.Event{Type: events.PlanResolved, Time: t.start, Action: "up"}
+	for _, phase := range plan.Phases(services) {
+		p := events.Phase{Order: phase.Order}
+		for _, service := range phase.Services {
+			p.Services = append(p.Services, events.Service{Service: service.ServiceName, ComposeFile: service.ComposeFile})
+		}
+		resolved.Phases = append(resolved.Phases, p)
+	}
+	observer.Event(resolved)
+	return t
+}
+
+// Write passes p through and times the services of the complete lines in it.
+func (t *scriptTimer) Write(p []byte) (int, error) {
+	n, err := t.out.Write(p)
+	t.partial = append(t.partial, p[:n]...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.line(strings.TrimRight(string(t.partial[:i]), "\r"))
+		t.partial = t.partial[i+1:]
+	}
+	return n, err
+}
+
+// line times the service a line of the script announces, if any.
+func (t *scriptTimer) line(line string) {
+	health := "started"
+	match := startingLine.FindStringSubmatch(line)
+	if match == nil {
+		health = "already running (shared)"
+		if match = skippingLine.FindStringSubmatch(line); match == nil {
+			return
+		}
+	}
+	for i := t.next; i < len(t.services); i++ {
+		if t.services[i].ServiceName != match[1] {
+			continue
+		}
+		now := time.Now()
+		t.finishService(now, nil)
+		service := t.services[i]
+		if t.phase != nil && t.phase.Phase != service.Order {
+			t.finishPhase(now)
+		}
+		if t.phase == nil {
+			t.phase = &events.Event{Type: events.PhaseComplete, Action: "up", Phase: service.Order}
+			t.phaseSince = now
+		}
+		t.current = &events.Event{Type: events.ServiceHealthy, Action: "up", Service: service.ServiceName, ComposeFile: service.ComposeFile, Phase: service.Order, Health: health}
+		t.since = now
+		t.next = i + 1
+		return
+	}
+}
+
+// Finish records the end of the run, which failed when err is not nil.
+func (t *scriptTimer) Finish(err error) {
+	now := time.Now()
+	t.finishService(now, err)
+	if err == nil {
+		t.finishPhase(now)
+	}
+	finished := events.Event{Type: events.RunFinished, Time: now, Action: "up", Duration: now.Sub(t.start)}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	t.observer.Event(finished)
+}
+
+// finishService sends the event of the current service, failed when err is
+// not nil.
+func (t *scriptTimer) finishService(now time.Time, err error) {
+	if t.current == nil {
+		return
+	}
+	event := *t.current
+	event.Time, event.Duration = now, now.Sub(t.since)
+	event.Command = event.Duration
+	if err != nil {
+		event.Type, event.Health, event.Error = events.ServiceFailed, "", err.Error()
+	}
+	t.observer.Event(event)
+	t.current = nil
+}
+
+// finishPhase sends the event of the current phase.
+func (t *scriptTimer) finishPhase(now time.Time) {
+	if t.phase == nil {
+		return
+	}
+	event := *t.phase
+	event.Time, event.Duration = now, now.Sub(t.phaseSince)
+	t.observer.Event(event)
+	t.phase = nil
+}
+
+// runScript runs the script at scriptPath for action ("up" or "down") with
+// args, adding env to its environment. The timing of up runs is added to the
+// history of the project.
+func runScript(projectDir, scriptPath, action string, args, env []string) error {
+	services, err := readScriptServices(scriptPath)
+	if action != "up" || err != nil {
+		_, err := runDockermiScript(scriptPath, action, args, env, os.Stdout)
+		return err
+	}
+
+	root, _ := scriptServices(projectDir, scriptPath)
+	recorder := timing.NewRecorder(scriptLabel(scriptPath))
+	timer := newScriptTimer(os.Stdout, recorder, services)
+	_, err = runDockermiScript(scriptPath, action, args, env, timer)
+	timer.Finish(err)
+	return saveTiming(root, recorder, false, err)
+}
+
+// saveTiming adds the run recorded by recorder to the history of the project
+// at root and, with profile, prints how it compares with the previous runs.
+// err is the outcome of the run, returned unless printing the report fails.
+func saveTiming(root string, recorder *timing.Recorder, profile bool, err error) error {
+	historyDir := timing.Dir(root)
+	if _, serr := timing.Save(historyDir, recorder.Run); serr != nil {
+		color.Yellow("Warning: cannot record the run timing: %v", serr)
+		return err
+	}
+	if !profile {
+		return err
+	}
+
+	fmt.Println()
+	runs, lerr := timing.Load(historyDir, recorder.Run.Script, defaultStatsRuns)
+	if lerr != nil {
+		runs = []timing.Run{recorder.Run}
+	}
+	if perr := printTimingReport(runs, defaultRegressionThreshold); perr != nil && err == nil {
+		err = perr
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package dockermi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mkhuda/dockermi/internal/dockercompose"
+	"github.com/mkhuda/dockermi/internal/plan"
+	"github.com/mkhuda/dockermi/internal/registry"
+	"github.com/mkhuda/dockermi/internal/runner"
+	"github.com/mkhuda/dockermi/internal/script"
+	"github.com/mkhuda/dockermi/internal/watch"
+	DockermiTypes "github.com/mkhuda/dockermi/types"
+)
+
+// handleWatchCommand handles 'dockermi watch', regenerating the dockermi script
+// of projectDir and the group scripts created from it whenever compose files
+// change, until interrupted.
+func handleWatchCommand(projectDir string, args []string, opts generateOptions) (string, error) {
+	watchOpts, args, err := parseWatchOptions(args)
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("unknown argument for watch: %s", args[0])
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	color.Green("Watching compose files in %s (press Ctrl+C to stop)", projectDir)
+	err = watch.Watch(ctx, projectDir, watchOpts, func(change watch.Change) {
+		printChange(projectDir, change)
+		if err := regenerateWatchedScripts(projectDir, opts); err != nil {
+			color.Red("Error regenerating scripts: %v", err)
+		}
+	})
+	return projectScriptPath(projectDir), err
+}
+
+// parseWatchOptions reads the --interval and --debounce durations and returns
+// the remaining args.
+func parseWatchOptions(args []string) (watch.Options, []string, error) {
+	var opts watch.Options
+	for _, option := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"interval", &opts.Interval},
+		{"debounce", &opts.Debounce},
+	} {
+		value, rest, err := popFlagValue(args, option.name)
+		if err != nil {
+			return opts, nil, err
+		}
+		args = rest
+		if value == "" {
+			continue
+		}
+		if *option.value, err = time.ParseDuration(value); err != nil {
+			return opts, nil, fmt.Errorf("invalid --%s: %w", option.name, err)
+		}
+	}
+	return opts, args, nil
+}
+
+// printChange prints the compose files of a change relative to projectDir.
+func printChange(projectDir string, change watch.Change) {
+	fmt.Println()
+	color.Cyan("[%s] Compose files changed:", time.Now().Format("15:04:05"))
+	for _, path := range change.Created {
+		color.Green("  created  %s", relativePath(projectDir, path))
+	}
+	for _, path := range change.Modified {
+		color.Yellow("  modified %s", relativePath(projectDir, path))
+	}
+	for _, path := range change.Removed {
+		color.Red("  removed  %s", relativePath(projectDir, path))
+	}
+}
+
+// regenerateWatchedScripts regenerates the dockermi script of projectDir and the
+// group scripts created from it, printing how each plan changed. Scripts keep
+// the options recorded in their header.
+func regenerateWatchedScripts(projectDir string, opts generateOptions) error {
+	scriptPath := projectScriptPath(projectDir)
+	scriptOpts := opts
+	if header, found, err := script.ReadHeader(scriptPath); err == nil && found {
+		scriptOpts = optionsFromHeader(header)
+		scriptOpts.Overwrite = opts.Overwrite
+	}
+
+	services, err := dockercompose.FindServices(projectDir, scriptOpts.Force)
+	if err != nil {
+		return err
+	}
+	printWatchedPlan(scriptPath, services)
+	if _, err := generateScripts(projectDir, scriptOpts); err != nil {
+		return err
+	}
+
+	reg, err := registry.Load()
+	if err != nil {
+		return err
+	}
+	var groups map[string][]DockermiTypes.ServiceScript
+	for _, e := range reg.List() {
+		if e.Root != projectDir {
+			continue
+		}
+		if groups == nil {
+			if groups, err = dockercompose.FindServicesWithKey(projectDir); err != nil {
+				return err
+			}
+		}
+
+		groupOpts := opts
+		if header, found, err := script.ReadHeader(e.ScriptPath); err == nil && found {
+			groupOpts = optionsFromHeader(header)
+			groupOpts.Overwrite = opts.Overwrite
+		}
+		printWatchedPlan(e.ScriptPath, groups[e.Key])
+		if _, err := createDockermiScript(projectDir, e.Key, groupOpts); err != nil {
+			color.Red("Error regenerating the %s group script: %v", e.Key, err)
+		}
+	}
+	return nil
+}
+
+// printWatchedPlan prints how the plan of the script at scriptPath changes when
+// it is regenerated for services.
+func printWatchedPlan(scriptPath string, services []DockermiTypes.ServiceScript) {
+	existing, err := script.ReadScriptServices(scriptPath)
+	if err != nil && !os.IsNotExist(err) {
+		color.Red("%s: %v", scriptPath, err)
+		return
+	}
+
+	current := append([]DockermiTypes.ServiceScript(nil), services...)
+	plan.Sort(current)
+
+	diff := plan.Compare(existing, current)
+	if diff.Empty() {
+		color.Blue("%s: plan unchanged", filepath.Base(scriptPath))
+		return
+	}
+	color.Yellow("%s: plan changed", filepath.Base(scriptPath))
+	printPlanDiff(diff)
+}
+
+// relativePath returns path relative to root when it is below root.
+func relativePath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// hotReloadServices watches the compose files scriptPath was generated from and,
+// after every change, recreates the services whose definition changed and
+// their dependents, starts new services and stops removed ones, until interrupted.
+// args are passed to every up command and env is added to its environment.
+func hotReloadServices(projectDir, scriptPath string, args []string, dryRun bool, env []string, opts watch.Options) error {
+	root, discover := scriptServices(projectDir, scriptPath)
+	services, err := discover()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r := runner.Runner{Command: scriptCompose(scriptPath).Command, Env: env, Args: args, DryRun: dryRun}
+	color.Green("Watching compose files in %s for changes (press Ctrl+C to stop)", root)
+	return watch.Watch(ctx, root, opts, func(change watch.Change) {
+		printChange(root, change)
+
+		current, err := discover()
+		if err != nil {
+			color.Red("Error reading compose files: %v", err)
+			return
+		}
+		reload := plan.PlanReload(services, current)
+		services = current
+		if reload.Empty() {
+			color.Blue("No service definitions changed")
+			return
+		}
+
+		for _, service := range reload.Stop {
+			color.Red("  stop     %s (%s)", service.ServiceName, relativePath(root, service.ComposeFile))
+		}
+		for _, service := range reload.Recreate {
+			color.Yellow("  recreate %s (%s)", service.ServiceName, relativePath(root, service.ComposeFile))
+		}
+		for _, service := range reload.Start {
+			color.Green("  start    %s (%s)", service.ServiceName, relativePath(root, service.ComposeFile))
+		}
+		if err := r.Apply(reload); err != nil {
+			color.Red("Error reloading services: %v", err)
+		}
+	})
+}
+
+// scriptServices returns the directory the script at scriptPath was generated
+// from and a function finding its services there again, honouring the group
+// key and --force recorded in the script header.
+func scriptServices(projectDir, scriptPath string) (string, func() ([]DockermiTypes.ServiceScript, error)) {
+	header, found, err := script.ReadHeader(scriptPath)
+	if err != nil || !found {
+		return projectDir, func() ([]DockermiTypes.ServiceScript, error) {
+			return dockercompose.FindServices(projectDir, false)
+		}
+	}
+
+	if header.Key != "" {
+		return header.Root, func() ([]DockermiTypes.ServiceScript, error) {
+			groups, err := dockercompose.FindServicesWithKey(header.Root)
+			return groups[header.Key], err
+		}
+	}
+	return header.Root, func() ([]DockermiTypes.ServiceScript, error) {
+		return dockercompose.FindServices(header.Root, header.HasFlag("--force"))
+	}
+}
@@ -0,0 +1,87 @@
+package dockermi_test
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchUnchangedPlan(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+	writeCompose(t, projectDir, "app", service("api", "2")+service("web", "2"))
+	if out, err := runDockermi(t, projectDir); err != nil {
+		t.Fatalf("Error generating the script: %v\n%s", err, out)
+	}
+
+	cmd := dockermiCommand(t, projectDir, "watch", "--interval", "20ms", "--debounce", "20ms")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Error reading the watch output: %v", err)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Error starting watch: %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func(prefix string, timeout time.Duration) (string, bool) {
+		t.Helper()
+		deadline := time.After(timeout)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("watch exited before printing %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line, true
+				}
+			case <-deadline:
+				return "", false
+			}
+		}
+	}
+	if _, ok := next("Watching compose files", 10*time.Second); !ok {
+		t.Fatalf("Timed out waiting for watch to start")
+	}
+
+	// Changing the image of a service leaves the start plan as it is. The
+	// change is repeated until it is seen, as watch prints that it is
+	// watching before its watches are in place.
+	for i := 0; ; i++ {
+		image := fmt.Sprintf("busybox:%d", i)
+		writeCompose(t, projectDir, "db", strings.Replace(service("db", "1"), "busybox", image, 1))
+		line, ok := next("dockermi.sh: plan", 500*time.Millisecond)
+		if ok {
+			if line != "dockermi.sh: plan unchanged" {
+				t.Fatalf("Expected the plan to be unchanged, got: %s", line)
+			}
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Timed out waiting for watch to see the change")
+		}
+	}
+}
+
+func TestWatchUnknownArgument(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCompose(t, projectDir, "db", service("db", "1"))
+
+	out, err := runDockermi(t, projectDir, "watch", "--intervall", "2s")
+	if err == nil || !strings.Contains(out, "unknown argument for watch: --intervall") {
+		t.Fatalf("Expected the misspelled option to be rejected, got: %v\n%s", err, out)
+	}
+}
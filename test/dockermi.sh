@@ -0,0 +1,114 @@
+#!/bin/bash
+
+# dockermi:version v0.1.6
+# dockermi:generated 2026-10-19T05:50:46Z
+# dockermi:root /root/module/test
+# dockermi:source 895d825a1fca5564debb47d883aabff678c4563d1c63435eba95844d56b3152b  /root/module/test/dummy1/docker-compose.yml
+# dockermi:source 91fdb0e894b4bca21f833f4deec83058cf0908c0cdf65936c735260d9a2495d5  /root/module/test/dummy2/docker-compose.yml
+# dockermi:source 2c3998189bf05d207a9f7500b39f6cc5957e0b256b36a8192db7c57db972a79c  /root/module/test/multipleservices/docker-compose.yml
+# dockermi:source a8f7afbb7b53d0b6f0c64d2d20d3fd083adc18ba46f44fba1c02451e78a4e9b1  /root/module/test/nginx/docker-compose.yml
+# dockermi:source 2fe11294d5aff7b4299041b16c26920947fa7e14b11755b0f1fe284e62c42f72  /root/module/test/oldcompose/docker-compose.yml
+# dockermi:source 2b5e218b771a8d53b4223b3abedefd1f296831d9725b7538551e11a914125597  /root/module/test/postgres/docker-compose.yml
+# dockermi:source 5c710df96e1fbab66fdc128fd1001d9505bbce6cfe291bf0607625eb92afb5a8  /root/module/test/randomname/docker-compose-random.yml
+# dockermi:source b871aba39c3ff7c24906cb8a1521bcc91464116012a63ec63e2c86e0b929502f  /root/module/test/withkeys1 copy/docker-compose.yml
+# dockermi:source ac824a579b0c504ad646dad378db24cd9e919ff32b585cfbbec764284556d85d  /root/module/test/withkeys1/docker-compose.yml
+# dockermi:file /root/module/test/dummy1/docker-compose.yml
+# dockermi:file /root/module/test/dummy2/docker-compose.yml
+# dockermi:file /root/module/test/inactive/docker-compose.yml
+# dockermi:file /root/module/test/multipleservices/docker-compose.yml
+# dockermi:file /root/module/test/nginx/docker-compose.yml
+# dockermi:file /root/module/test/nolabels/docker-compose.yml
+# dockermi:file /root/module/test/oldcompose/docker-compose.yml
+# dockermi:file /root/module/test/postgres/docker-compose.yml
+# dockermi:file /root/module/test/randomname/docker-compose-random.yml
+# dockermi:file /root/module/test/withkeys1/docker-compose.yml
+# dockermi:file /root/module/test/withkeys1 copy/docker-compose.yml
+# dockermi:order 1  /root/module/test/dummy1/docker-compose.yml#dummy1
+# dockermi:order 2  /root/module/test/dummy2/docker-compose.yml#dummy2
+# dockermi:order 2  /root/module/test/multipleservices/docker-compose.yml#dummyservice1
+# dockermi:order 1  /root/module/test/nginx/docker-compose.yml#web
+# dockermi:order 2  /root/module/test/oldcompose/docker-compose.yml#oldcompose
+# dockermi:order 1  /root/module/test/postgres/docker-compose.yml#db
+# dockermi:order 2  /root/module/test/randomname/docker-compose-random.yml#randomcompose
+# dockermi:order 2  /root/module/test/withkeys1 copy/docker-compose.yml#servicewithkey2xx
+# dockermi:order 1  /root/module/test/withkeys1/docker-compose.yml#servicewithkey1x
+# dockermi:order 2  /root/module/test/withkeys1/docker-compose.yml#servicewithkey1xx
+
+# Usage: dockermi [up|down] [options]
+
+start_services() {
+    echo "Starting dummy1..."
+    docker-compose -f "/root/module/test/dummy1/docker-compose.yml" up -d "dummy1" "$@"
+    echo "Starting web..."
+    docker-compose -f "/root/module/test/nginx/docker-compose.yml" up -d "web" "$@"
+    echo "Starting db..."
+    docker-compose -f "/root/module/test/postgres/docker-compose.yml" up -d "db" "$@"
+    echo "Starting servicewithkey1x..."
+    docker-compose -f "/root/module/test/withkeys1/docker-compose.yml" up -d "servicewithkey1x" "$@"
+    echo "Starting dummy2..."
+    docker-compose -f "/root/module/test/dummy2/docker-compose.yml" up -d "dummy2" "$@"
+    echo "Starting dummyservice1..."
+    docker-compose -f "/root/module/test/multipleservices/docker-compose.yml" up -d "dummyservice1" "$@"
+    echo "Starting oldcompose..."
+    docker-compose -f "/root/module/test/oldcompose/docker-compose.yml" up -d "oldcompose" "$@"
+    echo "Starting randomcompose..."
+    docker-compose -f "/root/module/test/randomname/docker-compose-random.yml" up -d "randomcompose" "$@"
+    echo "Starting servicewithkey2xx..."
+    docker-compose -f "/root/module/test/withkeys1 copy/docker-compose.yml" up -d "servicewithkey2xx" "$@"
+    echo "Starting servicewithkey1xx..."
+    docker-compose -f "/root/module/test/withkeys1/docker-compose.yml" up -d "servicewithkey1xx" "$@"
+}
+
+stop_services() {
+    echo "Stopping servicewithkey1xx..."
+    docker-compose -f "/root/module/test/withkeys1/docker-compose.yml" stop "servicewithkey1xx" "$@"
+    echo "Stopping servicewithkey2xx..."
+    docker-compose -f "/root/module/test/withkeys1 copy/docker-compose.yml" stop "servicewithkey2xx" "$@"
+    echo "Stopping randomcompose..."
+    docker-compose -f "/root/module/test/randomname/docker-compose-random.yml" stop "randomcompose" "$@"
+    echo "Stopping oldcompose..."
+    docker-compose -f "/root/module/test/oldcompose/docker-compose.yml" stop "oldcompose" "$@"
+    echo "Stopping dummyservice1..."
+    docker-compose -f "/root/module/test/multipleservices/docker-compose.yml" stop "dummyservice1" "$@"
+    echo "Stopping dummy2..."
+    docker-compose -f "/root/module/test/dummy2/docker-compose.yml" stop "dummy2" "$@"
+    echo "Stopping servicewithkey1x..."
+    docker-compose -f "/root/module/test/withkeys1/docker-compose.yml" stop "servicewithkey1x" "$@"
+    echo "Stopping db..."
+    docker-compose -f "/root/module/test/postgres/docker-compose.yml" stop "db" "$@"
+    echo "Stopping web..."
+    docker-compose -f "/root/module/test/nginx/docker-compose.yml" stop "web" "$@"
+    echo "Stopping dummy1..."
+    docker-compose -f "/root/module/test/dummy1/docker-compose.yml" stop "dummy1" "$@"
+}
+
+cleanup() {
+    echo "Caught interrupt signal. Stopping services..."
+    exit 0
+}
+
+# Register the cleanup function to be called on SIGINT (Ctrl+C)
+trap cleanup SIGINT
+
+if [ "$#" -lt 1 ]; then
+    echo "Invalid argument!"
+    echo "Usage: $0 [up|down] [options]"
+    exit 1
+fi
+
+ACTION=$1
+shift
+
+case "$ACTION" in
+    up)
+        start_services "$@"
+        ;;
+    down)
+        stop_services "$@"
+        ;;
+    *)
+        echo "Invalid argument: $ACTION"
+        echo "Usage: $0 [up|down] [options]"
+        exit 1
+        ;;
+esac
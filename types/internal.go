@@ -5,6 +5,10 @@ type ServiceScript struct {
 	Order       string
 	ServiceName string
 	ComposeFile string
+	Keys        []string // Keys are the dockermi.key groups the service belongs to
+	Shared      bool     // Shared is true when the service belongs to more than one dockermi.key group
+	Inactive    bool     // Inactive is true when the service lacks dockermi labels or has dockermi.active=false
+	Definition  Service  // Definition is the service as parsed from the compose file
 }
 
 // ServiceScriptReturn represent the return of some internal methods
@@ -12,10 +16,22 @@ type ServiceScriptReturn []ServiceScript
 
 // Service represents a service in the docker-compose.yml file.
 type Service struct {
-	Name   string
-	Image  string            `yaml:"image"`
-	Ports  []string          `yaml:"ports"`
-	Labels map[string]string `yaml:"labels"`
+	Name        string
+	Image       string            `yaml:"image"`
+	Ports       []string          `yaml:"ports"`
+	Labels      map[string]string `yaml:"labels"`
+	Environment map[string]string `yaml:"environment"`
+	Volumes     []string          `yaml:"volumes"`
+	Healthcheck *Healthcheck      `yaml:"healthcheck"`
+	DependsOn   []string          `yaml:"depends_on"`
+}
+
+// Healthcheck represents the healthcheck of a service in the docker-compose.yml file.
+type Healthcheck struct {
+	Test     []string `yaml:"test"`
+	Interval string   `yaml:"interval"`
+	Timeout  string   `yaml:"timeout"`
+	Retries  int      `yaml:"retries"`
 }
 
 // DockerCompose represents the structure of the docker-compose.yml file.
@@ -0,0 +1,23 @@
+package utils
+
+import "os"
+
+// MkdirShared creates dir, and its missing parents, and makes dir writable by
+// the group regardless of the umask, so that everyone in the group can use it.
+// An existing directory without group write permission, e.g. created by an
+// older version, gets it added when it is owned by the current user.
+func MkdirShared(dir string) error {
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0070 != 0070 {
+		if err := os.Chmod(dir, perm|0070); err != nil && !os.IsPermission(err) {
+			return err
+		}
+	}
+	return nil
+}
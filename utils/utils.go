@@ -16,19 +16,102 @@ and the 'dockermi.sh' script is created in the current directory where the 'dock
 
 Commands:
     create <service-key>   Generate a dockermi.sh script for the specified service key.
+    create --all           Regenerate the group script of every dockermi.key found in the current directory.
+    check                  Compare existing scripts with what would be generated now; exits non-zero on drift.
     up [options]           Start the Docker services defined in the dockermi.sh file in the current directory.
     down [options]         Stop the Docker services defined in the dockermi.sh file in the current directory.
+    restart [options]      Stop, then start the Docker services defined in the dockermi.sh file.
+    status [--group <key>] Show the container state and health of every service, read from the Docker Engine API,
+                           and who holds the run lock.
+    history [options]      Show who ran up and down in this project and how it went, from .dockermi/audit.jsonl.
+    stats [--group <key>]  Compare the timing of the last 'up' run with the previous runs and flag regressions.
+    export systemd         Write systemd units starting the services on boot in dockermi order.
+    export k8s             Convert the services into Kubernetes Deployments and Services.
+    graph                  Print the start plan as a dependency graph (Graphviz dot, Mermaid or JSON).
+    watch                  Regenerate the scripts of the current directory whenever compose files change.
+    cache clear            Remove the parsed compose files cached in ~/.dockermi/cache.
+    groups [list]          List the group scripts created with 'dockermi create'.
+    groups show <key>      Show where a group script came from and when it was created.
+    groups context <key> [<context> | --host <host> | --clear]
+                           Show or set the default docker context or engine host of a group.
+    groups rm <key>...     Remove group scripts and their registry entries.
+
+Export systemd options:
+    --output <dir>         Directory the unit files are written to (default: ./systemd).
+    --per-phase            Generate one unit per dockermi.order instead of one per service.
+    --group-targets        Also generate a dockermi-<key>.target unit per dockermi.key group.
+
+Export k8s options:
+    --output <file>        File the manifests are written to (default: stdout).
+    --namespace-by-key     Place services in a namespace named after their dockermi.key.
+    --wait-image <image>   Image of the init containers waiting for earlier services (default: busybox:1.36).
+
+Graph options:
+    --format <format>      Graph format: dot (default), mermaid or json.
+    --cluster <mode>       Cluster services by compose file (file, default) or by dockermi.key (key).
+    --output <file>        File the graph is written to (default: stdout).
+
+Watch options (also for 'up --watch'):
+    --interval <duration>  How often compose files are scanned for changes (default: 1s).
+    --debounce <duration>  How long files must stay unchanged before scripts are regenerated (default: 500ms).
+
+Stats options:
+    --last <n>             Number of runs to compare, the last one included (default: 5).
+    --threshold <percent>  How much slower than its average a service must be to be flagged (default: 20%%).
+
+History options:
+    --service <name>       Only runs that included the service.
+    --user <name>          Only runs by the user.
+    --group <key>          Only runs of the group script for <key>.
+    --since <time>         Only runs since a date (2006-01-02), a time (2006-01-02 15:04) or a duration ago (24h).
+    --until <time>         Only runs before a time, or up to the end of a date.
+    --last <n>             Number of runs printed, 0 for all (default: 20).
+    --json                 Print the records as JSON lines.
+
+Up/Down options:
+    --group <key>          Run the group script for <key> from ~/.dockermi instead of ./dockermi.sh.
+    --dry-run              Print the compose commands in order instead of running them.
+    --watch                Up only: keep running and recreate services whose compose definition changed.
+    --events <file>        Run the services phase by phase and append JSON lines events to <file>.
+    --junit <file>         Run the services phase by phase and write a JUnit XML report to <file>.
+    --health-timeout <d>   With --events, --junit or --profile: how long up waits for each service to be healthy (default: 2m).
+    --profile              Up only: run the services phase by phase and print how long each took, compared with previous runs.
+    --wait-lock            Wait for another up, down or restart of the same services to finish instead of failing.
+    --lock-timeout <d>     With --wait-lock: how long to wait for the lock (default: 10m).
+    --context <name>       Run the compose commands against a docker context (also for 'status').
+    --host <host>          Run the compose commands against an engine host: unix://, tcp:// or ssh:// (also for 'status').
 
 Options:
     --help                 Display this help message and exit.
     --version              Display current installed version.
     --force                Force create dockermi.sh from all valid docker-compose files, ignoring dockermi labels convention.
+    --template <path>      Generate scripts from a custom text/template file instead of the built-in template.
+    --dry-run              Show the changes to existing scripts instead of writing them.
+    --no-cache             Parse every compose file again instead of using ~/.dockermi/cache.
+    --overwrite            Replace existing scripts that have no dockermi header (e.g. written by hand).
+    --format <format>      Script format: bash (dockermi.sh, default), sh (POSIX dockermi.sh), powershell (dockermi.ps1),
+                           make (dockermi.mk) or just (dockermi.just).
+    --compose <tool>       Compose tool the scripts invoke: docker-compose, docker (docker compose),
+                           podman-docker (docker compose of the podman-docker shim), podman-compose
+                           or podman (podman compose), or auto to detect it from PATH.
+                           Defaults to $DOCKERMI_COMPOSE, or else docker-compose.
+    --context <name>       Docker context the generated scripts use unless DOCKER_CONTEXT or DOCKER_HOST is set.
+    --host <host>          Engine host the generated scripts use unless DOCKER_CONTEXT or DOCKER_HOST is set.
     
 Examples:
     dockermi                        # Generates a dockermi.sh script in the current directory.
     dockermi create myservicekey    # [Experimental] Create a script for the specified service key.
     dockermi up -d --build          # Start services with the --build option.
     dockermi down --remove-orphans   # Stop services and remove orphan containers.
+    dockermi up --group myservicekey # Start a group script from any directory.
+    dockermi up --dry-run --build   # Print the compose commands 'up --build' would run.
+    dockermi --format powershell    # Generate a dockermi.ps1 script for Windows.
+    dockermi --compose podman-compose # Generate a dockermi.sh script for Podman.
+    dockermi up --context build-box # Start services on the engine of the build-box docker context.
+    dockermi up --junit report.xml  # Start services, waiting for each to be healthy, and write a JUnit report.
+    dockermi history --service db --since 24h # Who started or stopped db in the last day.
+    dockermi stats --last 10        # Compare the timing of the last up run with the 9 runs before it.
+    dockermi graph | dot -Tsvg > plan.svg # Draw the start plan with Graphviz.
 
 `, version)
 }